@@ -7,6 +7,8 @@ import (
 
 	"github.com/developia-II/language-translator-backend/internal/database"
 	"github.com/developia-II/language-translator-backend/internal/handlers"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
@@ -27,9 +29,19 @@ func main() {
 	}
 	defer database.Disconnect()
 
+	if err := database.Migrate(); err != nil {
+		log.Fatal("Failed to migrate database:", err)
+	}
+
+	if err := services.InitLLM(); err != nil {
+		log.Fatal("Invalid LLM provider config:\n", err)
+	}
+	services.SetRedactionLogger(handlers.RecordRedaction)
+
 	// Create Fiber app
 	app := fiber.New(fiber.Config{
 		ErrorHandler: handlers.ErrorHandler,
+		BodyLimit:    25 * 1024 * 1024, // audio uploads for STT
 	})
 
 	// Middleware
@@ -38,7 +50,7 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins: os.Getenv("FRONTEND_URL"),
 		AllowHeaders: "Origin, Content-Type, Accept, Authorization",
-		AllowMethods: "GET, POST, PUT, DELETE",
+		AllowMethods: "GET, POST, PUT, PATCH, DELETE",
 	}))
 	app.Use(limiter.New(limiter.Config{
 		Max:        100,
@@ -54,25 +66,56 @@ func main() {
 	auth.Post("/login", handlers.Login)
 	auth.Get("/me", handlers.AuthMiddleware, handlers.Me)
 
+	// Shared conversation links (public, read-only)
+	api.Get("/shared/:token", handlers.GetSharedConversation)
+
 	// Protected routes
 	api.Use(handlers.AuthMiddleware)
 
 	// Translation routes
-	api.Post("/translate", handlers.Translate)
+	api.Post("/translate", handlers.QuotaMiddleware, handlers.Translate)
+	api.Post("/translate/speech", handlers.QuotaMiddleware, handlers.TranslateSpeech)
+	api.Post("/translate/image", handlers.QuotaMiddleware, handlers.TranslateImage)
 	api.Get("/translations", handlers.GetTranslations)
+	api.Get("/translations/:id/audio", handlers.GetTranslationAudio)
+	api.Get("/clips/:id/audio", handlers.GetAudioClip)
 
 	// TTS route
 	api.Post("/tts", handlers.TTS)
 
+	// STT route
+	api.Post("/stt", handlers.STT)
+
+	// Interpreter sessions (live translation over WebSocket)
+	api.Post("/interpreter/sessions", handlers.CreateInterpreterSession)
+	api.Get("/interpreter/sessions/:id", handlers.GetInterpreterSession)
+	api.Post("/interpreter/sessions/:id/join", handlers.JoinInterpreterSession)
+	api.Post("/interpreter/sessions/:id/close", handlers.CloseInterpreterSession)
+	api.Get("/interpreter/sessions/:id/ws", handlers.QuotaMiddleware, handlers.InterpreterUpgrade, websocket.New(handlers.InterpreterSocket))
+
 	// Feedback routes
 	api.Post("/feedback", handlers.SubmitFeedback)
 	api.Get("/feedback/:translationId", handlers.GetFeedback)
 
 	// Chat routes (protected)
 	api.Use(handlers.AuthMiddleware)
-	api.Post("/chat", handlers.Chat)
+	api.Post("/chat", handlers.QuotaMiddleware, handlers.Chat)
+	api.Post("/chat/stream", handlers.QuotaMiddleware, handlers.ChatStream)
 	api.Get("/conversations", handlers.GetConversations)
 	api.Get("/conversations/:id", handlers.GetConversation)
+	api.Patch("/conversations/:id", handlers.UpdateConversation)
+	api.Delete("/conversations/:id", handlers.DeleteConversation)
+	api.Post("/conversations/:id/restore", handlers.RestoreConversation)
+	api.Get("/conversations/:id/messages", handlers.GetConversationMessages)
+	api.Get("/conversations/:id/export", handlers.ExportConversation)
+	api.Post("/conversations/:id/shares", handlers.CreateShare)
+	api.Get("/conversations/:id/shares", handlers.GetShares)
+	api.Delete("/conversations/:id/shares/:shareId", handlers.RevokeShare)
+	api.Post("/conversations/:id/regenerate", handlers.QuotaMiddleware, handlers.RegenerateMessage)
+	api.Post("/conversations/:id/branch", handlers.SwitchBranch)
+	api.Put("/conversations/:id/messages/:msgId", handlers.QuotaMiddleware, handlers.EditMessage)
+	api.Get("/conversations/:id/messages/:msgId/audio", handlers.GetMessageAudio)
+	api.Get("/usage", handlers.GetUsage)
 
 	// Admin routes (protected by Auth + Admin middleware)
 	admin := api.Group("/admin")
@@ -85,6 +128,28 @@ func main() {
 	admin.Get("/metrics/translation-volume", handlers.GetTranslationVolume)
 	admin.Get("/metrics/feedback-distribution", handlers.GetFeedbackDistribution)
 	admin.Get("/metrics/translation-by-language", handlers.GetTranslationByLanguage)
+	admin.Get("/metrics/token-usage", handlers.GetTokenUsage)
+	admin.Get("/metrics/token-usage-by-user", handlers.GetTokenUsageByUser)
+	admin.Get("/redactions", handlers.GetRedactionLog)
+	admin.Get("/moderation", handlers.GetModerationEvents)
+	// Pronunciation lexicon for TTS
+	admin.Get("/lexicon", handlers.GetLexicon)
+	admin.Post("/lexicon", handlers.CreateLexiconEntry)
+	admin.Post("/lexicon/preview", handlers.PreviewLexicon)
+	admin.Put("/lexicon/:id", handlers.UpdateLexiconEntry)
+	admin.Delete("/lexicon/:id", handlers.DeleteLexiconEntry)
+	// System prompt templates per persona
+	admin.Get("/prompts", handlers.GetPrompts)
+	admin.Post("/prompts", handlers.CreatePromptVersion)
+	admin.Post("/prompts/preview", handlers.PreviewPrompt)
+	admin.Post("/prompts/:id/activate", handlers.ActivatePromptVersion)
+	// Medical knowledge base for grounded answers
+	admin.Get("/knowledge", handlers.GetKnowledgeDocuments)
+	admin.Post("/knowledge", handlers.CreateKnowledgeDocument)
+	admin.Post("/knowledge/search", handlers.SearchKnowledge)
+	admin.Post("/knowledge/reindex", handlers.ReindexKnowledge)
+	admin.Get("/knowledge/:id", handlers.GetKnowledgeDocument)
+	admin.Delete("/knowledge/:id", handlers.DeleteKnowledgeDocument)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -0,0 +1,50 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/hajimehoshi/go-mp3"
+)
+
+// Decode sniffs data and decodes WAV or MP3 into a Buffer. The content
+// type is only used as a hint when the bytes are ambiguous.
+func Decode(data []byte, contentType string) (*Buffer, error) {
+	ct := strings.ToLower(contentType)
+	switch {
+	case IsWAV(data):
+		return DecodeWAV(data)
+	case isMP3(data) || strings.Contains(ct, "mpeg") || strings.Contains(ct, "mp3"):
+		return decodeMP3(data)
+	}
+	return nil, fmt.Errorf("unsupported audio format %q", contentType)
+}
+
+func isMP3(data []byte) bool {
+	if len(data) >= 3 && string(data[0:3]) == "ID3" {
+		return true
+	}
+	// MPEG frame sync: 11 set bits
+	return len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0
+}
+
+func decodeMP3(data []byte) (*Buffer, error) {
+	dec, err := mp3.NewDecoder(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("decode mp3: %w", err)
+	}
+	// go-mp3 always yields 16-bit little-endian stereo
+	pcm, err := io.ReadAll(dec)
+	if err != nil {
+		return nil, fmt.Errorf("decode mp3: %w", err)
+	}
+	n := len(pcm) / 2
+	samples := make([]float32, n)
+	for i := 0; i < n; i++ {
+		samples[i] = float32(int16(binary.LittleEndian.Uint16(pcm[i*2:]))) / 32768
+	}
+	return &Buffer{SampleRate: dec.SampleRate(), Channels: 2, Samples: samples}, nil
+}
@@ -0,0 +1,147 @@
+package audio
+
+import "math"
+
+// Loudness returns the integrated loudness of b in LUFS following
+// ITU-R BS.1770-4 (K-weighting, 400ms blocks, absolute and relative gates).
+// It returns -Inf for silent input.
+func Loudness(b *Buffer) float64 {
+	if b.SampleRate == 0 || b.Channels == 0 || len(b.Samples) == 0 {
+		return math.Inf(-1)
+	}
+
+	frames := len(b.Samples) / b.Channels
+	weighted := make([][]float64, b.Channels)
+	for ch := 0; ch < b.Channels; ch++ {
+		shelf := newKShelf(float64(b.SampleRate))
+		hp := newKHighPass(float64(b.SampleRate))
+		w := make([]float64, frames)
+		for i := 0; i < frames; i++ {
+			w[i] = hp.process(shelf.process(float64(b.Samples[i*b.Channels+ch])))
+		}
+		weighted[ch] = w
+	}
+
+	block := int(0.4 * float64(b.SampleRate))
+	step := block / 4
+	if frames < block {
+		block = frames
+		step = frames
+	}
+
+	var powers []float64
+	for start := 0; start+block <= frames; start += step {
+		var p float64
+		for ch := 0; ch < b.Channels; ch++ {
+			var sum float64
+			for _, v := range weighted[ch][start : start+block] {
+				sum += v * v
+			}
+			p += sum / float64(block)
+		}
+		powers = append(powers, p)
+	}
+
+	gate := func(threshold float64) []float64 {
+		var kept []float64
+		for _, p := range powers {
+			if blockLoudness(p) > threshold {
+				kept = append(kept, p)
+			}
+		}
+		return kept
+	}
+
+	abs := gate(-70)
+	if len(abs) == 0 {
+		return math.Inf(-1)
+	}
+	relative := blockLoudness(mean(abs)) - 10
+	rel := gate(relative)
+	if len(rel) == 0 {
+		return math.Inf(-1)
+	}
+	return blockLoudness(mean(rel))
+}
+
+// Normalize applies a gain so b measures targetLUFS, limited so the peak
+// stays under -1 dBFS.
+func Normalize(b *Buffer, targetLUFS float64) {
+	measured := Loudness(b)
+	if math.IsInf(measured, -1) {
+		return
+	}
+	gain := math.Pow(10, (targetLUFS-measured)/20)
+
+	var peak float64
+	for _, s := range b.Samples {
+		peak = math.Max(peak, math.Abs(float64(s)))
+	}
+	if ceiling := math.Pow(10, -1.0/20); peak*gain > ceiling {
+		gain = ceiling / peak
+	}
+	for i := range b.Samples {
+		b.Samples[i] = float32(float64(b.Samples[i]) * gain)
+	}
+}
+
+func blockLoudness(power float64) float64 {
+	return -0.691 + 10*math.Log10(power)
+}
+
+func mean(v []float64) float64 {
+	var sum float64
+	for _, x := range v {
+		sum += x
+	}
+	return sum / float64(len(v))
+}
+
+type biquad struct {
+	b0, b1, b2, a1, a2 float64
+	x1, x2, y1, y2     float64
+}
+
+func (f *biquad) process(x float64) float64 {
+	y := f.b0*x + f.b1*f.x1 + f.b2*f.x2 - f.a1*f.y1 - f.a2*f.y2
+	f.x2, f.x1 = f.x1, x
+	f.y2, f.y1 = f.y1, y
+	return y
+}
+
+// newKShelf is the BS.1770 pre-filter (head-related high shelf), derived for
+// any sample rate instead of using the 48kHz table values.
+func newKShelf(fs float64) *biquad {
+	const g, q, fc = 4.0, 1 / math.Sqrt2, 1500.0
+	a := math.Pow(10, g/40)
+	w0 := 2 * math.Pi * fc / fs
+	alpha := math.Sin(w0) / (2 * q)
+	cos := math.Cos(w0)
+	sa := 2 * math.Sqrt(a) * alpha
+
+	a0 := (a + 1) - (a-1)*cos + sa
+	return &biquad{
+		b0: a * ((a + 1) + (a-1)*cos + sa) / a0,
+		b1: -2 * a * ((a - 1) + (a+1)*cos) / a0,
+		b2: a * ((a + 1) + (a-1)*cos - sa) / a0,
+		a1: 2 * ((a - 1) - (a+1)*cos) / a0,
+		a2: ((a + 1) - (a-1)*cos - sa) / a0,
+	}
+}
+
+// newKHighPass is the BS.1770 RLB weighting high-pass filter.
+func newKHighPass(fs float64) *biquad {
+	const q, fc = 0.5, 38.0
+	w0 := 2 * math.Pi * fc / fs
+	alpha := math.Sin(w0) / (2 * q)
+	cos := math.Cos(w0)
+
+	a0 := 1 + alpha
+	return &biquad{
+		b0: (1 + cos) / 2 / a0,
+		b1: -(1 + cos) / a0,
+		b2: (1 + cos) / 2 / a0,
+		a1: -2 * cos / a0,
+		a2: (1 - alpha) / a0,
+	}
+}
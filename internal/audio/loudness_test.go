@@ -0,0 +1,34 @@
+package audio
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestLoudnessReferenceTone(t *testing.T) {
+	// BS.1770: a 1 kHz sine at -20 dBFS on one channel reads -23.01 LUFS
+	// (a full-scale one reads -3.01).
+	for _, rate := range []int{48000, 44100, 16000} {
+		got := Loudness(sine(1000, 0.1, 3, rate, 1))
+		if math.Abs(got-(-23.01)) > 0.5 {
+			t.Errorf("%d Hz: loudness = %.2f LUFS, want -23.01 ± 0.5", rate, got)
+		}
+	}
+}
+
+func TestNormalizeReachesTarget(t *testing.T) {
+	for _, target := range []float64{-16, -23} {
+		b := sine(1000, 0.02, 3, 24000, 1)
+		Normalize(b, target)
+		if got := Loudness(b); math.Abs(got-target) > 0.5 {
+			t.Errorf("normalised to %.2f LUFS, want %.0f ± 0.5", got, target)
+		}
+	}
+}
+
+func TestLoudnessOfSilence(t *testing.T) {
+	if got := Loudness(Silence(time.Second, 16000, 1)); !math.IsInf(got, -1) {
+		t.Errorf("silence measured %v, want -Inf", got)
+	}
+}
@@ -0,0 +1,105 @@
+package audio
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Output formats supported by Process. Opus-in-Ogg would need libopus via
+// cgo, so the compact option is raw 16-bit PCM (audio/L16) instead.
+const (
+	FormatWAV = "wav"
+	FormatPCM = "pcm"
+)
+
+// Options controls the post-processing stage.
+type Options struct {
+	Format     string
+	SampleRate int     // 0 keeps the source rate
+	Mono       bool    // downmix to one channel
+	TargetLUFS float64 // 0 disables loudness normalisation
+	Trim       bool    // strip leading and trailing silence
+}
+
+// trimThreshold is the level (dBFS) below which audio counts as silence.
+const trimThreshold = -50.0
+
+// trimPadding is kept around speech so words aren't clipped.
+const trimPadding = 100 * time.Millisecond
+
+// Process decodes provider audio and returns it re-encoded per opts along
+// with its content type.
+func Process(data []byte, contentType string, opts Options) ([]byte, string, error) {
+	buf, err := Decode(data, contentType)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if opts.Mono {
+		buf = Mono(buf)
+	}
+	if opts.Trim {
+		buf = TrimSilence(buf)
+	}
+	buf = Resample(buf, opts.SampleRate)
+	if opts.TargetLUFS != 0 {
+		Normalize(buf, opts.TargetLUFS)
+	}
+
+	switch opts.Format {
+	case FormatWAV, "":
+		return EncodeWAV(buf), "audio/wav", nil
+	case FormatPCM:
+		return EncodePCM(buf), fmt.Sprintf("audio/L16;rate=%d;channels=%d", buf.SampleRate, buf.Channels), nil
+	}
+	return nil, "", fmt.Errorf("unsupported output format %q", opts.Format)
+}
+
+// TrimSilence drops leading and trailing frames quieter than trimThreshold,
+// keeping trimPadding either side.
+func TrimSilence(b *Buffer) *Buffer {
+	frames := len(b.Samples) / b.Channels
+	threshold := float32(math.Pow(10, trimThreshold/20))
+
+	loud := func(i int) bool {
+		for ch := 0; ch < b.Channels; ch++ {
+			s := b.Samples[i*b.Channels+ch]
+			if s > threshold || s < -threshold {
+				return true
+			}
+		}
+		return false
+	}
+
+	start, end := 0, frames
+	for start < frames && !loud(start) {
+		start++
+	}
+	for end > start && !loud(end-1) {
+		end--
+	}
+	if start >= end {
+		return b
+	}
+
+	pad := int(trimPadding.Seconds() * float64(b.SampleRate))
+	start = max(0, start-pad)
+	end = min(frames, end+pad)
+	return &Buffer{
+		SampleRate: b.SampleRate,
+		Channels:   b.Channels,
+		Samples:    b.Samples[start*b.Channels : end*b.Channels],
+	}
+}
+
+// EncodePCM writes b as headerless big-endian 16-bit PCM, the byte order
+// audio/L16 (RFC 2586) requires.
+func EncodePCM(b *Buffer) []byte {
+	out := make([]byte, len(b.Samples)*2)
+	for i, s := range b.Samples {
+		binary.BigEndian.PutUint16(out[i*2:], uint16(toInt16(s)))
+	}
+	return out
+}
@@ -0,0 +1,45 @@
+package audio
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTrimSilence(t *testing.T) {
+	const rate = 16000
+	lead, tail := Silence(time.Second, rate, 1), Silence(700*time.Millisecond, rate, 1)
+	speech := &Buffer{SampleRate: rate, Channels: 1, Samples: make([]float32, rate/2)}
+	for i := range speech.Samples {
+		speech.Samples[i] = 0.25
+	}
+	speech.Samples[len(speech.Samples)/2] = 0 // a quiet sample inside speech is kept
+
+	padded := Silence(0, rate, 1)
+	for _, part := range []*Buffer{lead, speech, tail} {
+		if err := padded.Append(part); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := TrimSilence(padded)
+	pad := int(trimPadding.Seconds() * rate)
+	if want := len(speech.Samples) + 2*pad; len(got.Samples) != want {
+		t.Fatalf("got %d samples, want %d", len(got.Samples), want)
+	}
+	for i, s := range got.Samples {
+		inSpeech := i >= pad && i < pad+len(speech.Samples)
+		if !inSpeech && s != 0 {
+			t.Fatalf("sample %d in the padding is %v", i, s)
+		}
+		if inSpeech && s != speech.Samples[i-pad] {
+			t.Fatalf("sample %d is %v, want %v", i, s, speech.Samples[i-pad])
+		}
+	}
+}
+
+func TestTrimSilenceKeepsSilentInput(t *testing.T) {
+	b := Silence(time.Second, 8000, 2)
+	if got := TrimSilence(b); len(got.Samples) != len(b.Samples) {
+		t.Errorf("silent input trimmed to %d samples", len(got.Samples))
+	}
+}
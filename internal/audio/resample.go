@@ -0,0 +1,79 @@
+package audio
+
+import "math"
+
+// resampleTaps is the half-width of the windowed-sinc kernel. 16 taps each
+// side keeps aliasing well below speech noise floors without being slow.
+const resampleTaps = 16
+
+// Resample converts b to the given sample rate with a Hann-windowed sinc
+// interpolator, low-pass filtering when downsampling.
+func Resample(b *Buffer, rate int) *Buffer {
+	if rate <= 0 || rate == b.SampleRate || len(b.Samples) == 0 {
+		return b
+	}
+
+	ratio := float64(rate) / float64(b.SampleRate)
+	cutoff := math.Min(1, ratio)
+	inFrames := len(b.Samples) / b.Channels
+	outFrames := int(math.Ceil(float64(inFrames) * ratio))
+	out := &Buffer{SampleRate: rate, Channels: b.Channels, Samples: make([]float32, outFrames*b.Channels)}
+
+	width := float64(resampleTaps) / cutoff
+	for i := 0; i < outFrames; i++ {
+		center := float64(i) / ratio
+		lo := int(math.Ceil(center - width))
+		hi := int(math.Floor(center + width))
+		if lo < 0 {
+			lo = 0
+		}
+		if hi >= inFrames {
+			hi = inFrames - 1
+		}
+		for ch := 0; ch < b.Channels; ch++ {
+			var sum, norm float64
+			for j := lo; j <= hi; j++ {
+				x := (float64(j) - center) * cutoff
+				w := sinc(x) * hann(x/float64(resampleTaps))
+				sum += float64(b.Samples[j*b.Channels+ch]) * w
+				norm += w
+			}
+			if norm != 0 {
+				sum /= norm
+			}
+			out.Samples[i*b.Channels+ch] = float32(sum)
+		}
+	}
+	return out
+}
+
+// Mono averages all channels into one.
+func Mono(b *Buffer) *Buffer {
+	if b.Channels <= 1 {
+		return b
+	}
+	frames := len(b.Samples) / b.Channels
+	out := &Buffer{SampleRate: b.SampleRate, Channels: 1, Samples: make([]float32, frames)}
+	for i := 0; i < frames; i++ {
+		var sum float32
+		for ch := 0; ch < b.Channels; ch++ {
+			sum += b.Samples[i*b.Channels+ch]
+		}
+		out.Samples[i] = sum / float32(b.Channels)
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+func hann(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return 0.5 + 0.5*math.Cos(math.Pi*x)
+}
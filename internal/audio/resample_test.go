@@ -0,0 +1,59 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// toneFrequency estimates the frequency of a mono tone from its rising zero
+// crossings, ignoring the edges where the resampling kernel is truncated.
+func toneFrequency(b *Buffer) float64 {
+	skip := b.SampleRate / 20
+	s := b.Samples[skip : len(b.Samples)-skip]
+	first, last, crossings := -1, -1, 0
+	for i := 1; i < len(s); i++ {
+		if s[i-1] < 0 && s[i] >= 0 {
+			if first < 0 {
+				first = i
+			} else {
+				crossings++
+			}
+			last = i
+		}
+	}
+	return float64(crossings) * float64(b.SampleRate) / float64(last-first)
+}
+
+func TestResamplePreservesToneAndLength(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to int
+	}{
+		{"down 44.1k to 16k", 44100, 16000},
+		{"down 48k to 22.05k", 48000, 22050},
+		{"up 16k to 48k", 16000, 48000},
+	}
+	const freq, seconds = 440.0, 1.0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Resample(sine(freq, 0.5, seconds, tt.from, 1), tt.to)
+
+			if out.SampleRate != tt.to {
+				t.Errorf("sample rate = %d, want %d", out.SampleRate, tt.to)
+			}
+			if want := int(seconds * float64(tt.to)); len(out.Samples) != want {
+				t.Errorf("got %d samples, want %d", len(out.Samples), want)
+			}
+			if got := toneFrequency(out); math.Abs(got-freq) > freq*0.005 {
+				t.Errorf("tone at %.2f Hz, want %.0f Hz", got, freq)
+			}
+		})
+	}
+}
+
+func TestResampleSameRateIsUnchanged(t *testing.T) {
+	in := sine(440, 0.5, 0.1, 16000, 1)
+	if out := Resample(in, 16000); out != in {
+		t.Error("resampling to the same rate made a copy")
+	}
+}
@@ -0,0 +1,200 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Buffer holds decoded PCM audio as interleaved float32 samples in [-1, 1].
+type Buffer struct {
+	SampleRate int
+	Channels   int
+	Samples    []float32
+}
+
+// Duration returns the playback length of the buffer.
+func (b *Buffer) Duration() time.Duration {
+	if b.SampleRate == 0 || b.Channels == 0 {
+		return 0
+	}
+	frames := len(b.Samples) / b.Channels
+	return time.Duration(frames) * time.Second / time.Duration(b.SampleRate)
+}
+
+// Silence returns a buffer of d seconds of silence matching the given format.
+func Silence(d time.Duration, sampleRate, channels int) *Buffer {
+	frames := int(d.Seconds() * float64(sampleRate))
+	if frames < 0 {
+		frames = 0
+	}
+	return &Buffer{
+		SampleRate: sampleRate,
+		Channels:   channels,
+		Samples:    make([]float32, frames*channels),
+	}
+}
+
+// Append adds other to the end of b. Both buffers must share the same format.
+func (b *Buffer) Append(other *Buffer) error {
+	if other == nil {
+		return nil
+	}
+	if b.SampleRate != other.SampleRate || b.Channels != other.Channels {
+		return fmt.Errorf("audio format mismatch: %dHz/%dch vs %dHz/%dch",
+			b.SampleRate, b.Channels, other.SampleRate, other.Channels)
+	}
+	b.Samples = append(b.Samples, other.Samples...)
+	return nil
+}
+
+// IsWAV reports whether data looks like a RIFF/WAVE file.
+func IsWAV(data []byte) bool {
+	return len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE"
+}
+
+// DecodeWAV parses a PCM (8/16/24/32-bit) or IEEE float WAV file.
+func DecodeWAV(data []byte) (*Buffer, error) {
+	if !IsWAV(data) {
+		return nil, fmt.Errorf("not a WAV file")
+	}
+
+	var (
+		format        uint16
+		channels      int
+		sampleRate    int
+		bitsPerSample int
+		gotFmt        bool
+	)
+
+	pos := 12
+	for pos+8 <= len(data) {
+		id := string(data[pos : pos+4])
+		size := int(binary.LittleEndian.Uint32(data[pos+4 : pos+8]))
+		pos += 8
+		// Streaming encoders (e.g. espeak-ng --stdout) write 0xFFFFFFFF sizes;
+		// clamp to what is actually present.
+		if size < 0 || pos+size > len(data) {
+			size = len(data) - pos
+		}
+		chunk := data[pos : pos+size]
+
+		switch id {
+		case "fmt ":
+			if len(chunk) < 16 {
+				return nil, fmt.Errorf("short fmt chunk")
+			}
+			format = binary.LittleEndian.Uint16(chunk[0:2])
+			channels = int(binary.LittleEndian.Uint16(chunk[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(chunk[4:8]))
+			bitsPerSample = int(binary.LittleEndian.Uint16(chunk[14:16]))
+			// WAVE_FORMAT_EXTENSIBLE carries the real format in the sub-format GUID
+			if format == 0xFFFE && len(chunk) >= 26 {
+				format = binary.LittleEndian.Uint16(chunk[24:26])
+			}
+			gotFmt = true
+		case "data":
+			if !gotFmt {
+				return nil, fmt.Errorf("data chunk before fmt chunk")
+			}
+			if channels <= 0 || sampleRate <= 0 {
+				return nil, fmt.Errorf("invalid WAV header: %d channels at %dHz", channels, sampleRate)
+			}
+			samples, err := decodeSamples(chunk, format, bitsPerSample)
+			if err != nil {
+				return nil, err
+			}
+			return &Buffer{SampleRate: sampleRate, Channels: channels, Samples: samples}, nil
+		}
+
+		pos += size
+		if size%2 == 1 {
+			pos++ // chunks are word aligned
+		}
+	}
+
+	return nil, fmt.Errorf("WAV file has no data chunk")
+}
+
+func decodeSamples(chunk []byte, format uint16, bits int) ([]float32, error) {
+	switch {
+	case format == 1 && bits == 8:
+		out := make([]float32, len(chunk))
+		for i, v := range chunk {
+			out[i] = (float32(v) - 128) / 128
+		}
+		return out, nil
+	case format == 1 && bits == 16:
+		n := len(chunk) / 2
+		out := make([]float32, n)
+		for i := 0; i < n; i++ {
+			out[i] = float32(int16(binary.LittleEndian.Uint16(chunk[i*2:]))) / 32768
+		}
+		return out, nil
+	case format == 1 && bits == 24:
+		n := len(chunk) / 3
+		out := make([]float32, n)
+		for i := 0; i < n; i++ {
+			b := chunk[i*3:]
+			v := int32(b[0]) | int32(b[1])<<8 | int32(int8(b[2]))<<16
+			out[i] = float32(v) / 8388608
+		}
+		return out, nil
+	case format == 1 && bits == 32:
+		n := len(chunk) / 4
+		out := make([]float32, n)
+		for i := 0; i < n; i++ {
+			out[i] = float32(int32(binary.LittleEndian.Uint32(chunk[i*4:]))) / 2147483648
+		}
+		return out, nil
+	case format == 3 && bits == 32:
+		n := len(chunk) / 4
+		out := make([]float32, n)
+		for i := 0; i < n; i++ {
+			out[i] = math.Float32frombits(binary.LittleEndian.Uint32(chunk[i*4:]))
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unsupported WAV encoding: format=%d bits=%d", format, bits)
+}
+
+// EncodeWAV writes b as a 16-bit PCM WAV file.
+func EncodeWAV(b *Buffer) []byte {
+	dataSize := len(b.Samples) * 2
+	var buf bytes.Buffer
+	buf.Grow(44 + dataSize)
+
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, uint32(36+dataSize))
+	buf.WriteString("WAVE")
+
+	buf.WriteString("fmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, uint16(1))
+	binary.Write(&buf, binary.LittleEndian, uint16(b.Channels))
+	binary.Write(&buf, binary.LittleEndian, uint32(b.SampleRate))
+	binary.Write(&buf, binary.LittleEndian, uint32(b.SampleRate*b.Channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(b.Channels*2))
+	binary.Write(&buf, binary.LittleEndian, uint16(16))
+
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, uint32(dataSize))
+	pcm := make([]byte, dataSize)
+	for i, s := range b.Samples {
+		binary.LittleEndian.PutUint16(pcm[i*2:], uint16(toInt16(s)))
+	}
+	buf.Write(pcm)
+
+	return buf.Bytes()
+}
+
+func toInt16(s float32) int16 {
+	if s > 1 {
+		s = 1
+	} else if s < -1 {
+		s = -1
+	}
+	return int16(math.Round(float64(s) * 32767))
+}
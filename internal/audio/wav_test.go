@@ -0,0 +1,61 @@
+package audio
+
+import (
+	"math"
+	"testing"
+)
+
+// sine returns seconds of a tone at freq Hz with the given peak amplitude.
+func sine(freq, amplitude float64, seconds float64, sampleRate, channels int) *Buffer {
+	frames := int(seconds * float64(sampleRate))
+	b := &Buffer{SampleRate: sampleRate, Channels: channels, Samples: make([]float32, frames*channels)}
+	for i := 0; i < frames; i++ {
+		v := float32(amplitude * math.Sin(2*math.Pi*freq*float64(i)/float64(sampleRate)))
+		for ch := 0; ch < channels; ch++ {
+			b.Samples[i*channels+ch] = v
+		}
+	}
+	return b
+}
+
+func TestWAVRoundTrip(t *testing.T) {
+	tests := []struct {
+		name       string
+		sampleRate int
+		channels   int
+	}{
+		{"mono 16kHz", 16000, 1},
+		{"stereo 44.1kHz", 44100, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in := sine(440, 0.8, 0.25, tt.sampleRate, tt.channels)
+			in.Samples[0], in.Samples[1] = 1, -1 // full scale survives
+
+			out, err := DecodeWAV(EncodeWAV(in))
+			if err != nil {
+				t.Fatalf("DecodeWAV: %v", err)
+			}
+			if out.SampleRate != in.SampleRate || out.Channels != in.Channels || len(out.Samples) != len(in.Samples) {
+				t.Fatalf("got %dHz/%dch/%d samples, want %dHz/%dch/%d",
+					out.SampleRate, out.Channels, len(out.Samples), in.SampleRate, in.Channels, len(in.Samples))
+			}
+			for i := range in.Samples {
+				if d := math.Abs(float64(out.Samples[i] - in.Samples[i])); d > 2.0/32767 { // 16-bit quantisation
+					t.Fatalf("sample %d: got %v, want %v", i, out.Samples[i], in.Samples[i])
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeWAVRejectsBadInput(t *testing.T) {
+	for name, data := range map[string][]byte{
+		"not RIFF": []byte("ID3 this is not a wav file"),
+		"no data":  EncodeWAV(&Buffer{SampleRate: 8000, Channels: 1})[:36],
+	} {
+		if _, err := DecodeWAV(data); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// Migrate creates the indexes the handlers rely on and moves chat messages
+// and interpreter utterances that are still embedded in their parent
+// documents into their own collections. It is safe to run on every start.
+func Migrate() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	if err := ensureIndexes(ctx); err != nil {
+		return fmt.Errorf("create indexes: %w", err)
+	}
+	if err := migrateEmbeddedMessages(ctx); err != nil {
+		return fmt.Errorf("migrate embedded messages: %w", err)
+	}
+	if err := linkMessages(ctx); err != nil {
+		return fmt.Errorf("link messages: %w", err)
+	}
+	if err := migrateEmbeddedUtterances(ctx); err != nil {
+		return fmt.Errorf("migrate embedded utterances: %w", err)
+	}
+	return nil
+}
+
+func ensureIndexes(ctx context.Context) error {
+	_, err := GetCollection("messages").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}},
+		{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "parentId", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("conversations").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "userId", Value: 1}, {Key: "updatedAt", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("prompt_templates").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "persona", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("knowledge_chunks").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "documentId", Value: 1}, {Key: "index", Value: 1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("usage").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "userId", Value: 1}, {Key: "createdAt", Value: 1}}},
+		{Keys: bson.D{{Key: "createdAt", Value: 1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("redaction_log").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "service", Value: 1}, {Key: "createdAt", Value: -1}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("conversation_shares").Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{Keys: bson.D{{Key: "tokenHash", Value: 1}}, Options: options.Index().SetUnique(true)},
+		{Keys: bson.D{{Key: "conversationId", Value: 1}, {Key: "createdAt", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = GetCollection("interpreter_utterances").Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "sessionId", Value: 1}, {Key: "createdAt", Value: 1}},
+	})
+	return err
+}
+
+// migrateEmbeddedMessages copies each conversation's embedded messages into
+// the messages collection and then drops the array. Messages keep their
+// IDs and are only inserted when missing, so a run interrupted between the
+// two steps is simply repeated.
+func migrateEmbeddedMessages(ctx context.Context) error {
+	conversations := GetCollection("conversations")
+	messages := GetCollection("messages")
+
+	cursor, err := conversations.Find(ctx,
+		bson.M{"messages": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"messages": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID       primitive.ObjectID `bson:"_id"`
+			Messages []bson.M           `bson:"messages"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		for _, m := range doc.Messages {
+			if _, ok := m["_id"]; !ok {
+				m["_id"] = primitive.NewObjectID()
+			}
+			m["conversationId"] = doc.ID
+		}
+		if err := insertMissing(ctx, messages, doc.Messages); err != nil {
+			return err
+		}
+
+		count, err := messages.CountDocuments(ctx, bson.M{"conversationId": doc.ID})
+		if err != nil {
+			return err
+		}
+		set := bson.M{"messageCount": count}
+		if n := len(doc.Messages); n > 0 {
+			if content, ok := doc.Messages[n-1]["content"].(string); ok {
+				set["lastMessage"] = utils.Preview(content)
+			}
+		}
+		_, err = conversations.UpdateOne(ctx,
+			bson.M{"_id": doc.ID},
+			bson.M{
+				"$unset": bson.M{"messages": ""},
+				"$set":   set,
+				"$inc":   bson.M{"version": 1},
+			},
+		)
+		if err != nil {
+			return err
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if migrated > 0 {
+		log.Printf("Migrated embedded messages of %d conversations", migrated)
+	}
+
+	// Version checks compare against an existing field
+	_, err = conversations.UpdateMany(ctx,
+		bson.M{"version": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"version": 0}},
+	)
+	return err
+}
+
+// insertMissing inserts the docs whose _id is not in collection yet and
+// leaves the others untouched, so a copy that was interrupted half way can
+// be repeated.
+func insertMissing(ctx context.Context, collection *mongo.Collection, docs []bson.M) error {
+	if len(docs) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, len(docs))
+	for i, d := range docs {
+		fields := bson.M{}
+		for k, v := range d {
+			if k != "_id" {
+				fields[k] = v
+			}
+		}
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": d["_id"]}).
+			SetUpdate(bson.M{"$setOnInsert": fields}).
+			SetUpsert(true)
+	}
+	_, err := collection.BulkWrite(ctx, writes)
+	return err
+}
+
+// linkMessages turns the flat message lists of conversations created before
+// branching into a single chain of parent links ending at the active leaf,
+// and moves the summary marker from a message count to a message ID.
+func linkMessages(ctx context.Context) error {
+	conversations := GetCollection("conversations")
+	messages := GetCollection("messages")
+
+	cursor, err := conversations.Find(ctx,
+		bson.M{"activeLeafId": bson.M{"$exists": false}, "messageCount": bson.M{"$gt": 0}},
+		options.Find().SetProjection(bson.M{"summarizedCount": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	linked := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID              primitive.ObjectID `bson:"_id"`
+			SummarizedCount int                `bson:"summarizedCount"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+
+		msgCursor, err := messages.Find(ctx,
+			bson.M{"conversationId": doc.ID},
+			options.Find().
+				SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+				SetProjection(bson.M{"_id": 1}),
+		)
+		if err != nil {
+			return err
+		}
+		var ids []struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		err = msgCursor.All(ctx, &ids)
+		msgCursor.Close(ctx)
+		if err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			continue
+		}
+
+		var writes []mongo.WriteModel
+		for i := 1; i < len(ids); i++ {
+			writes = append(writes, mongo.NewUpdateOneModel().
+				SetFilter(bson.M{"_id": ids[i].ID}).
+				SetUpdate(bson.M{"$set": bson.M{"parentId": ids[i-1].ID}}))
+		}
+		if len(writes) > 0 {
+			if _, err := messages.BulkWrite(ctx, writes); err != nil {
+				return err
+			}
+		}
+
+		update := bson.M{
+			"$set":   bson.M{"activeLeafId": ids[len(ids)-1].ID},
+			"$unset": bson.M{"summarizedCount": ""},
+			"$inc":   bson.M{"version": 1},
+		}
+		if n := doc.SummarizedCount; n > 0 && n <= len(ids) {
+			update["$set"].(bson.M)["summarizedThrough"] = ids[n-1].ID
+		}
+		if _, err := conversations.UpdateOne(ctx, bson.M{"_id": doc.ID}, update); err != nil {
+			return err
+		}
+		linked++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if linked > 0 {
+		log.Printf("Linked message history of %d conversations", linked)
+	}
+	return nil
+}
+
+// migrateEmbeddedUtterances moves the transcripts of interpreter sessions
+// created before utterances had their own collection.
+func migrateEmbeddedUtterances(ctx context.Context) error {
+	sessions := GetCollection("interpreter_sessions")
+	utterances := GetCollection("interpreter_utterances")
+
+	cursor, err := sessions.Find(ctx,
+		bson.M{"utterances": bson.M{"$exists": true}},
+		options.Find().SetProjection(bson.M{"utterances": 1}),
+	)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	migrated := 0
+	for cursor.Next(ctx) {
+		var doc struct {
+			ID         primitive.ObjectID `bson:"_id"`
+			Utterances []bson.M           `bson:"utterances"`
+		}
+		if err := cursor.Decode(&doc); err != nil {
+			return err
+		}
+		for _, u := range doc.Utterances {
+			if _, ok := u["_id"]; !ok {
+				u["_id"] = primitive.NewObjectID()
+			}
+			u["sessionId"] = doc.ID
+		}
+		if err := insertMissing(ctx, utterances, doc.Utterances); err != nil {
+			return err
+		}
+		if _, err := sessions.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$unset": bson.M{"utterances": ""}}); err != nil {
+			return err
+		}
+		migrated++
+	}
+	if err := cursor.Err(); err != nil {
+		return err
+	}
+	if migrated > 0 {
+		log.Printf("Migrated transcripts of %d interpreter sessions", migrated)
+	}
+	return nil
+}
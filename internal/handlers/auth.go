@@ -5,11 +5,13 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/developia-II/language-translator-backend/internal/database"
 	"github.com/developia-II/language-translator-backend/internal/models"
 	"github.com/developia-II/language-translator-backend/utils"
+	"github.com/gofiber/contrib/websocket"
 	"github.com/gofiber/fiber/v2"
 	"github.com/golang-jwt/jwt/v5"
 	"go.mongodb.org/mongo-driver/bson"
@@ -122,12 +124,17 @@ func Login(c *fiber.Ctx) error {
 
 func AuthMiddleware(c *fiber.Ctx) error {
 	authHeader := c.Get("Authorization")
-	if authHeader == "" {
+	var tokenString string
+	if authHeader != "" {
+		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+	} else if allowsQueryToken(c) {
+		// <audio src> and browser WebSockets can't send headers
+		tokenString = c.Query("token")
+	}
+	if tokenString == "" {
 		return utils.ErrorResponse(c, fiber.StatusUnauthorized, "Missing authorization header")
 	}
 
-	tokenString := authHeader[7:] // Remove "Bearer " prefix
-
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		return []byte(os.Getenv("JWT_SECRET")), nil
 	})
@@ -145,6 +152,16 @@ func AuthMiddleware(c *fiber.Ctx) error {
 	return c.Next()
 }
 
+// allowsQueryToken reports whether the request is a GET for one of the
+// .../audio endpoints or a WebSocket upgrade, which accept ?token= instead
+// of a header.
+func allowsQueryToken(c *fiber.Ctx) bool {
+	if c.Method() != fiber.MethodGet {
+		return false
+	}
+	return strings.HasSuffix(c.Path(), "/audio") || websocket.IsWebSocketUpgrade(c)
+}
+
 // AdminMiddleware ensures the requester has role == "admin"
 func AdminMiddleware(c *fiber.Ctx) error {
 	role, _ := c.Locals("role").(string)
@@ -0,0 +1,413 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// Messages form a tree through ParentID. Editing a user message or
+// regenerating an answer adds a sibling instead of overwriting, and the
+// conversation's ActiveLeafID picks which branch is shown and continued.
+
+// pathBatch is how many messages of a branch walkPath loads per query, so
+// no single result comes near Mongo's 16 MB document limit.
+const pathBatch = 50
+
+// walkPath visits the messages from leafID up to the first one, newest
+// first, until visit returns false. Ancestors are looked up pathBatch at a
+// time, so callers that stop early don't read the whole branch.
+func walkPath(conversationID, leafID primitive.ObjectID, visit func(models.Message) bool) error {
+	next := &leafID
+	for next != nil {
+		pipeline := mongo.Pipeline{
+			{{Key: "$match", Value: bson.M{"_id": *next, "conversationId": conversationID}}},
+			{{Key: "$graphLookup", Value: bson.M{
+				"from":                    "messages",
+				"startWith":               "$parentId",
+				"connectFromField":        "parentId",
+				"connectToField":          "_id",
+				"as":                      "ancestors",
+				"depthField":              "depth",
+				"maxDepth":                pathBatch - 2,
+				"restrictSearchWithMatch": bson.M{"conversationId": conversationID},
+			}}},
+		}
+		cursor, err := database.GetCollection("messages").Aggregate(context.Background(), pipeline)
+		if err != nil {
+			return err
+		}
+
+		type ancestor struct {
+			models.Message `bson:",inline"`
+			Depth          int `bson:"depth"`
+		}
+		var results []struct {
+			models.Message `bson:",inline"`
+			Ancestors      []ancestor `bson:"ancestors"`
+		}
+		err = cursor.All(context.Background(), &results)
+		cursor.Close(context.Background())
+		if err != nil {
+			return err
+		}
+		if len(results) == 0 {
+			return nil
+		}
+
+		leaf := results[0]
+		sort.Slice(leaf.Ancestors, func(i, j int) bool { return leaf.Ancestors[i].Depth < leaf.Ancestors[j].Depth })
+		if !visit(leaf.Message) {
+			return nil
+		}
+		next = leaf.ParentID
+		for _, a := range leaf.Ancestors {
+			if !visit(a.Message) {
+				return nil
+			}
+			next = a.ParentID
+		}
+	}
+	return nil
+}
+
+// messagePath returns the messages from the first one down to leafID,
+// oldest first.
+func messagePath(conversationID, leafID primitive.ObjectID) ([]models.Message, error) {
+	path := []models.Message{}
+	err := walkPath(conversationID, leafID, func(m models.Message) bool {
+		path = append(path, m)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(path)
+	return path, nil
+}
+
+// activePath returns the messages on the conversation's active branch.
+func activePath(conversation *models.Conversation) ([]models.Message, error) {
+	if conversation.ActiveLeafID == nil {
+		return []models.Message{}, nil
+	}
+	return messagePath(conversation.ID, *conversation.ActiveLeafID)
+}
+
+// turnPath returns the part of the branch ending at leafID that a new turn
+// is prompted with, oldest first: from the last message the conversation
+// summary covers, or the whole branch when the summary isn't on it. It has
+// at least two messages when the branch does, so an answer can be
+// regenerated from its question.
+func turnPath(conversation *models.Conversation, leafID primitive.ObjectID) ([]models.Message, error) {
+	path := []models.Message{}
+	err := walkPath(conversation.ID, leafID, func(m models.Message) bool {
+		path = append(path, m)
+		summarized := conversation.SummarizedThrough != nil && m.ID == *conversation.SummarizedThrough
+		return !summarized || len(path) < 2
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Reverse(path)
+	return path, nil
+}
+
+// activeTurnPath is turnPath for the conversation's active branch.
+func activeTurnPath(conversation *models.Conversation) ([]models.Message, error) {
+	if conversation.ActiveLeafID == nil {
+		return []models.Message{}, nil
+	}
+	return turnPath(conversation, *conversation.ActiveLeafID)
+}
+
+// withSiblings fills in Siblings for messages that have alternatives.
+func withSiblings(conversationID primitive.ObjectID, msgs []models.Message) error {
+	if len(msgs) == 0 {
+		return nil
+	}
+	parents := make([]any, 0, len(msgs))
+	for _, m := range msgs {
+		if m.ParentID == nil {
+			parents = append(parents, nil)
+		} else {
+			parents = append(parents, *m.ParentID)
+		}
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1, "parentId": 1})
+	cursor, err := database.GetCollection("messages").Find(context.Background(),
+		bson.M{"conversationId": conversationID, "parentId": bson.M{"$in": parents}}, opts)
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(context.Background())
+
+	var nodes []models.Message
+	if err := cursor.All(context.Background(), &nodes); err != nil {
+		return err
+	}
+	children := map[primitive.ObjectID][]primitive.ObjectID{}
+	for _, n := range nodes {
+		key := primitive.NilObjectID
+		if n.ParentID != nil {
+			key = *n.ParentID
+		}
+		children[key] = append(children[key], n.ID)
+	}
+
+	for i := range msgs {
+		key := primitive.NilObjectID
+		if msgs[i].ParentID != nil {
+			key = *msgs[i].ParentID
+		}
+		if ids := children[key]; len(ids) > 1 {
+			msgs[i].Siblings = ids
+		}
+	}
+	return nil
+}
+
+// newestLeaf follows the most recent child from messageID down to a leaf.
+func newestLeaf(conversationID, messageID primitive.ObjectID) (primitive.ObjectID, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"_id": 1, "parentId": 1})
+	cursor, err := database.GetCollection("messages").Find(context.Background(),
+		bson.M{"conversationId": conversationID, "parentId": bson.M{"$ne": nil}}, opts)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	defer cursor.Close(context.Background())
+
+	var nodes []models.Message
+	if err := cursor.All(context.Background(), &nodes); err != nil {
+		return primitive.NilObjectID, err
+	}
+	newestChild := map[primitive.ObjectID]primitive.ObjectID{}
+	for _, n := range nodes {
+		newestChild[*n.ParentID] = n.ID // sorted oldest first, so the last write wins
+	}
+
+	leaf := messageID
+	for {
+		child, ok := newestChild[leaf]
+		if !ok {
+			return leaf, nil
+		}
+		leaf = child
+	}
+}
+
+// findMessage loads one message of the conversation.
+func findMessage(conversationID primitive.ObjectID, hex string) (*models.Message, int, error) {
+	msgObjID, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil, fiber.StatusBadRequest, fmt.Errorf("Invalid message ID")
+	}
+	var m models.Message
+	err = database.GetCollection("messages").FindOne(context.Background(), bson.M{"_id": msgObjID, "conversationId": conversationID}).Decode(&m)
+	if err != nil {
+		return nil, fiber.StatusNotFound, fmt.Errorf("Message not found")
+	}
+	return &m, fiber.StatusOK, nil
+}
+
+// RegenerateMessage answers the last user message on the active branch
+// again. The new answer becomes a sibling of the old one.
+func RegenerateMessage(c *fiber.Ctx) error {
+	if services.LLM() == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Chat is not configured")
+	}
+	var req models.RegenerateRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	path, err := activeTurnPath(conversation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+	if len(path) < 2 || path[len(path)-1].Role != "assistant" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "No assistant message to regenerate")
+	}
+
+	previous := path[len(path)-1]
+	lang := req.Language
+	if lang == "" {
+		lang = previous.Language
+	}
+
+	mode := req.Mode
+	if mode == "" && previous.RawOutput != "" {
+		mode = models.ChatModeTriage
+	}
+
+	assistantMessage, _, err := replyTo(conversation, path[:len(path)-1], answerOptions{
+		Lang:   lang,
+		Mode:   mode,
+		Locale: requestLocale(c, ""),
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "AI service error: "+err.Error())
+	}
+	err = appendMessages(conversation, assistantMessage)
+	if err == errConversationConflict {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Conversation was modified; reload and try again")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save conversation")
+	}
+
+	reply := []models.Message{assistantMessage}
+	if err := withSiblings(conversation.ID, reply); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	return c.JSON(models.ChatResponse{
+		ConversationID: conversation.ID.Hex(),
+		Message:        reply[0],
+		Conversation:   conversation,
+	})
+}
+
+// EditMessage replaces a user message with a new version, forking the
+// conversation at that point, and answers it. The original branch is kept.
+func EditMessage(c *fiber.Ctx) error {
+	if services.LLM() == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Chat is not configured")
+	}
+	var req models.EditMessageRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if verdict := moderateInput(c, req.Message); verdict.Flagged {
+		return refuseInput(c, verdict)
+	}
+
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	original, status, err := findMessage(conversation.ID, c.Params("msgId"))
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+	if original.Role != "user" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Only user messages can be edited")
+	}
+
+	lang := req.Language
+	if lang == "" {
+		lang = original.Language
+	}
+
+	path := []models.Message{}
+	if original.ParentID != nil {
+		path, err = turnPath(conversation, *original.ParentID)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+		}
+	}
+
+	userMessage := newMessage(conversation, original.ParentID, "user", req.Message, lang)
+	turn := append(path, userMessage)
+	assistantMessage, assessment, err := replyTo(conversation, turn, answerOptions{
+		Lang:   lang,
+		Mode:   req.Mode,
+		Locale: requestLocale(c, ""),
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "AI service error: "+err.Error())
+	}
+	userMessage = turn[len(turn)-1] // with its English version, if bridged
+	flagMessage(&userMessage, assessment)
+	err = appendMessages(conversation, userMessage, assistantMessage)
+	if err == errConversationConflict {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Conversation was modified; reload and try again")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save conversation")
+	}
+	recordEmergency(conversation, userMessage, assessment)
+
+	return c.JSON(models.ChatResponse{
+		ConversationID: conversation.ID.Hex(),
+		Message:        assistantMessage,
+		Conversation:   conversation,
+	})
+}
+
+// SwitchBranch makes the branch through the given message active, following
+// its newest replies, and returns the first page of that branch.
+func SwitchBranch(c *fiber.Ctx) error {
+	var req models.SwitchBranchRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	target, status, err := findMessage(conversation.ID, req.MessageID)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	leaf, err := newestLeaf(conversation.ID, target.ID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	err = updateConversation(conversation, bson.M{"activeLeafId": leaf})
+	if err == errConversationConflict {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Conversation was modified; reload and try again")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update conversation")
+	}
+
+	messages, next, err := messagePage(conversation, "", pageLimit(c, 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+	conversation.Messages = messages
+
+	return c.JSON(fiber.Map{
+		"conversation": conversation,
+		"nextCursor":   next,
+	})
+}
@@ -0,0 +1,256 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+)
+
+// defaultBridgeLanguages are the languages the chat model answers poorly
+// in directly.
+const defaultBridgeLanguages = "ha,ig,yo"
+
+// usesBridge reports whether answers in lang go through English: the
+// question is translated to English, the model answers in English and the
+// answer is translated back. CHAT_BRIDGE_LANGUAGES lists the languages
+// ("none" turns bridging off).
+func usesBridge(lang string) bool {
+	list, ok := os.LookupEnv("CHAT_BRIDGE_LANGUAGES")
+	if !ok {
+		list = defaultBridgeLanguages
+	}
+	lang = baseLang(lang)
+	if lang == "" || lang == "en" {
+		return false
+	}
+	for _, l := range strings.Split(list, ",") {
+		if baseLang(l) == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// bridgeChunkSize keeps each translation request under MyMemory's limit of
+// about 500 characters per query.
+const bridgeChunkSize = 450
+
+// bridgeWorkers bounds the translation requests one answer makes at once.
+const bridgeWorkers = 4
+
+var (
+	// bridgeMarkup is the list marker or heading a line starts with, kept
+	// out of the text sent for translation.
+	bridgeMarkup = regexp.MustCompile(`^\s*(?:[-*+•]|\d+[.)]|#{1,6})?\s*`)
+	// bridgeSentence matches every part of a line: text up to and including
+	// its closing punctuation, or a run of punctuation on its own.
+	bridgeSentence = regexp.MustCompile(`[^.!?]*(?:[.!?]+|$)\s*`)
+)
+
+// bridgeSegment is a piece of text translated on its own: the text plus
+// the markup and whitespace around it, which are kept as they are.
+type bridgeSegment struct {
+	prefix, text, suffix string
+}
+
+// bridgeSegments splits text into lines, and long lines into sentences
+// grouped up to bridgeChunkSize bytes, so Markdown structure survives and
+// no request is too long. A sentence longer than that is cut at a space,
+// or failing that between characters. Pieces without letters, such as
+// "!!!", are kept as they are rather than translated.
+func bridgeSegments(text string) []bridgeSegment {
+	var segs []bridgeSegment
+	for _, line := range strings.SplitAfter(text, "\n") {
+		prefix := bridgeMarkup.FindString(line)
+		body := strings.TrimRightFunc(line[len(prefix):], unicode.IsSpace)
+		suffix := line[len(prefix)+len(body):]
+		if body == "" {
+			if prefix+suffix != "" {
+				segs = append(segs, bridgeSegment{prefix: prefix + suffix})
+			}
+			continue
+		}
+
+		var pieces []string
+		for _, sentence := range bridgeSentence.FindAllString(body, -1) {
+			for len(sentence) > bridgeChunkSize {
+				cut := bridgeCut(sentence)
+				pieces = append(pieces, sentence[:cut])
+				sentence = sentence[cut:]
+			}
+			if n := len(pieces); n > 0 && len(pieces[n-1])+len(sentence) <= bridgeChunkSize {
+				pieces[n-1] += sentence
+			} else {
+				pieces = append(pieces, sentence)
+			}
+		}
+		for i, p := range pieces {
+			seg := bridgeSegment{text: strings.TrimRightFunc(p, unicode.IsSpace)}
+			seg.suffix = p[len(seg.text):]
+			if i == 0 {
+				seg.prefix = prefix
+			}
+			if i == len(pieces)-1 {
+				seg.suffix += suffix
+			}
+			if strings.IndexFunc(seg.text, unicode.IsLetter) < 0 {
+				seg.prefix, seg.text = seg.prefix+seg.text, ""
+			}
+			segs = append(segs, seg)
+		}
+	}
+	return segs
+}
+
+// bridgeCut is where to cut a sentence longer than bridgeChunkSize: after
+// its last space within the limit, or else at the last character boundary,
+// never between a letter and its tone marks.
+func bridgeCut(sentence string) int {
+	if i := strings.LastIndex(sentence[:bridgeChunkSize], " "); i >= 0 {
+		return i + 1
+	}
+	cut := bridgeChunkSize
+	for cut > 0 {
+		r, _ := utf8.DecodeRuneInString(sentence[cut:])
+		if utf8.RuneStart(sentence[cut]) && !unicode.Is(unicode.Mn, r) {
+			break
+		}
+		cut--
+	}
+	if cut == 0 {
+		// Nothing but marks: cut at the next character boundary instead
+		for cut = bridgeChunkSize; cut < len(sentence) && !utf8.RuneStart(sentence[cut]); cut++ {
+		}
+	}
+	return cut
+}
+
+// bridgeTexts translates texts from one language to another, a few at a
+// time, counting any LLM tokens on ctx. Empty texts are skipped; any
+// failure fails the whole call.
+func bridgeTexts(ctx context.Context, texts []string, from, to string) ([]string, error) {
+	out := make([]string, len(texts))
+	errs := make([]error, len(texts))
+	sem := make(chan struct{}, bridgeWorkers)
+	var wg sync.WaitGroup
+	for i, t := range texts {
+		if strings.TrimSpace(t) == "" {
+			continue
+		}
+		wg.Add(1)
+		go func(i int, t string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			translated, err := services.TranslateText(ctx, t, from, to)
+			if err == nil && strings.TrimSpace(translated) == "" {
+				err = fmt.Errorf("empty translation")
+			}
+			out[i], errs[i] = translated, err
+		}(i, t)
+	}
+	wg.Wait()
+	return out, errors.Join(errs...)
+}
+
+// bridgeTranslate translates text segment by segment.
+func bridgeTranslate(ctx context.Context, text, from, to string) (string, error) {
+	segs := bridgeSegments(text)
+	texts := make([]string, len(segs))
+	for i, seg := range segs {
+		texts[i] = seg.text
+	}
+	translated, err := bridgeTexts(ctx, texts, from, to)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	for i, seg := range segs {
+		b.WriteString(seg.prefix)
+		b.WriteString(translated[i])
+		b.WriteString(seg.suffix)
+	}
+	return b.String(), nil
+}
+
+// bridgeQuestion fills in the English version of a user message answered
+// in lang, if that answer is bridged. On failure the message is left as is
+// and the model sees the original text.
+func bridgeQuestion(ctx context.Context, m *models.Message, lang string) {
+	if !usesBridge(lang) || m.EnglishContent != "" || baseLang(m.Language) == "en" {
+		return
+	}
+	english, err := bridgeTranslate(ctx, m.Content, baseLang(m.Language), "en")
+	if err != nil {
+		log.Printf("Chat: failed to translate question to English: %v", err)
+		return
+	}
+	m.EnglishContent = english
+}
+
+// bridgeAnswer translates the model's English answer into lang. If that
+// fails the English answer is returned so the user still gets a reply.
+func bridgeAnswer(ctx context.Context, english, lang string) string {
+	translated, err := bridgeTranslate(ctx, english, "en", baseLang(lang))
+	if err != nil {
+		log.Printf("Chat: failed to translate answer to %s, sending English: %v", lang, err)
+		return english
+	}
+	return translated
+}
+
+// bridgeTriage translates a triage answer into lang: the items and the
+// headings, returning the translated structure and its rendering.
+// Urgency stays the English code clients switch on; only its rendered
+// wording is translated. If translation fails the English answer is
+// returned.
+func bridgeTriage(ctx context.Context, t *models.Triage, lang string) (*models.Triage, string) {
+	labels := services.EnglishTriageLabels
+	texts := []string{
+		labels.Urgency, labels.PossibleCauses, labels.SelfCare, labels.WarningSigns, labels.SeeDoctorIf,
+		strings.ReplaceAll(t.Urgency, "_", " "),
+	}
+	lists := [][]string{t.PossibleCauses, t.SelfCare, t.WarningSigns, t.SeeDoctorIf}
+	for _, items := range lists {
+		texts = append(texts, items...)
+	}
+
+	translated, err := bridgeTexts(ctx, texts, "en", baseLang(lang))
+	if err != nil {
+		log.Printf("Chat: failed to translate triage answer to %s, sending English: %v", lang, err)
+		return t, services.RenderTriage(t)
+	}
+
+	labels = services.TriageLabels{
+		Urgency:        translated[0],
+		PossibleCauses: translated[1],
+		SelfCare:       translated[2],
+		WarningSigns:   translated[3],
+		SeeDoctorIf:    translated[4],
+	}
+	urgency := translated[5]
+	rest := translated[6:]
+	take := func(items []string) []string {
+		out := append([]string{}, rest[:len(items)]...)
+		rest = rest[len(items):]
+		return out
+	}
+	out := &models.Triage{
+		Urgency:        t.Urgency,
+		PossibleCauses: take(t.PossibleCauses),
+		SelfCare:       take(t.SelfCare),
+		WarningSigns:   take(t.WarningSigns),
+		SeeDoctorIf:    take(t.SeeDoctorIf),
+	}
+	return out, services.RenderTriageWith(out, labels, urgency)
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"strings"
+	"testing"
+	"unicode"
+	"unicode/utf8"
+)
+
+func TestBridgeSegments(t *testing.T) {
+	long := strings.Repeat("Drink plenty of water and rest. ", 30)
+	yoruba := strings.Repeat("ẹ̀", 200) // no spaces; each letter carries a tone mark
+
+	tests := []struct {
+		name  string
+		text  string
+		texts []string // what is sent for translation, in order; nil skips the check
+	}{
+		{"plain", "Rest well.", []string{"Rest well."}},
+		{"markdown", "# Advice\n\n- Rest.\n1. Drink water\n", []string{"Advice", "", "Rest.", "Drink water"}},
+		{"punctuation line", "Line one\n!!!\nLine three", []string{"Line one", "", "Line three"}},
+		{"only punctuation", "?!", []string{""}},
+		{"leading ellipsis", "...and then it stopped.", []string{"...and then it stopped."}},
+		{"two sentences", "Rest. Drink water.", []string{"Rest. Drink water."}},
+		{"long sentences", long, nil},
+		{"long word", yoruba, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			segs := bridgeSegments(tt.text)
+
+			var b strings.Builder
+			var texts []string
+			for _, seg := range segs {
+				b.WriteString(seg.prefix + seg.text + seg.suffix)
+				texts = append(texts, seg.text)
+				if len(seg.text) > bridgeChunkSize {
+					t.Errorf("segment of %d bytes is over the limit", len(seg.text))
+				}
+				if !utf8.ValidString(seg.text) {
+					t.Errorf("segment %q is not valid UTF-8", seg.text)
+				}
+				if r, _ := utf8.DecodeRuneInString(seg.text); unicode.Is(unicode.Mn, r) {
+					t.Errorf("segment starts with a combining mark: %q", seg.text)
+				}
+			}
+			if b.String() != tt.text {
+				t.Errorf("segments rebuild %q, want %q", b.String(), tt.text)
+			}
+			if tt.texts != nil && strings.Join(texts, "|") != strings.Join(tt.texts, "|") {
+				t.Errorf("texts = %q, want %q", texts, tt.texts)
+			}
+		})
+	}
+}
@@ -2,13 +2,16 @@ package handlers
 
 import (
 	"context"
-	"strings"
-	"sync"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
 	"github.com/developia-II/language-translator-backend/internal/database"
 	"github.com/developia-II/language-translator-backend/internal/models"
@@ -16,30 +19,46 @@ import (
 	"github.com/developia-II/language-translator-backend/utils"
 )
 
-var (
-	groqOnce    sync.Once
-	groqService *services.GroqService
-)
-
-func Chat(c *fiber.Ctx) error {
-
-	groqOnce.Do(func() { groqService = services.NewGroqService() })
-	var req models.ChatRequest
-	if err := c.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
-	}
+// errConversationConflict means the conversation changed since it was read.
+var errConversationConflict = errors.New("conversation was modified concurrently")
 
-	if err := utils.Validate.Struct(req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
-	}
+// answerOptions are the per-request settings for generating an answer.
+type answerOptions struct {
+	Lang   string
+	Mode   string // models.ChatModeText or models.ChatModeTriage
+	Locale string // fills the {{.Locale}} prompt variable
+	// UserName fills the {{.UserName}} prompt variable; set by
+	// withAccountName.
+	UserName string
+}
 
-	userID := c.Locals("userId").(string)
-	userObjID, _ := primitive.ObjectIDFromHex(userID)
+// withAccountName fills in the conversation owner's name for the prompt
+// and has it redacted from the LLM calls made with the returned context,
+// since no cue phrase introduces it.
+func withAccountName(ctx context.Context, conversation *models.Conversation, opts *answerOptions) context.Context {
+	opts.UserName = userDisplayName(conversation.UserID)
+	return services.WithPIINames(ctx, opts.UserName)
+}
 
-	// Get or create conversation
+// chatConversation loads the conversation named in req, or creates a new
+// one titled after the first message. A persona in req is checked and
+// becomes the conversation's persona.
+func chatConversation(userObjID primitive.ObjectID, req models.ChatRequest) (*models.Conversation, int, error) {
 	var conversation models.Conversation
 	conversationCollection := database.GetCollection("conversations")
 
+	persona := ""
+	if req.Persona != "" {
+		persona = normalizePersona(req.Persona)
+		tmpl, err := activePrompt(persona)
+		if err != nil {
+			return nil, fiber.StatusInternalServerError, fmt.Errorf("Failed to load persona")
+		}
+		if tmpl == nil {
+			return nil, fiber.StatusBadRequest, fmt.Errorf("Unknown persona %q", persona)
+		}
+	}
+
 	if req.ConversationID == "" {
 		// Create new conversation
 		title := req.Message
@@ -50,140 +69,342 @@ func Chat(c *fiber.Ctx) error {
 			ID:        primitive.NewObjectID(),
 			UserID:    userObjID,
 			Title:     title,
-			Messages:  []models.Message{},
+			Persona:   persona,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
 
 		_, err := conversationCollection.InsertOne(context.Background(), conversation)
 		if err != nil {
-			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create conversation")
+			return nil, fiber.StatusInternalServerError, fmt.Errorf("Failed to create conversation")
 		}
 	} else {
 		// Get existing conversation
 		convObjID, _ := primitive.ObjectIDFromHex(req.ConversationID)
-		err := conversationCollection.FindOne(context.Background(), bson.M{"_id": convObjID, "userId": userObjID}).Decode(&conversation)
+		err := conversationCollection.FindOne(context.Background(), bson.M{"_id": convObjID, "userId": userObjID, "deletedAt": nil}).Decode(&conversation)
 		if err != nil {
-			return utils.ErrorResponse(c, fiber.StatusNotFound, "Conversation not found")
+			return nil, fiber.StatusNotFound, fmt.Errorf("Conversation not found")
+		}
+
+		if persona != "" && persona != normalizePersona(conversation.Persona) {
+			if err := updateConversation(&conversation, bson.M{"persona": persona}); err != nil {
+				return nil, fiber.StatusConflict, fmt.Errorf("Failed to switch persona; try again")
+			}
 		}
 	}
+	return &conversation, fiber.StatusOK, nil
+}
 
-	// Add user message to conversation
-	userMessage := models.Message{
-		ID:        primitive.NewObjectID(),
-		Role:      "user",
-		Content:   req.Message,
-		Language:  req.Language,
-		CreatedAt: time.Now(),
+// newMessage builds a message belonging to conversation that follows parent
+// (nil for the first message).
+func newMessage(conversation *models.Conversation, parent *primitive.ObjectID, role, content, lang string) models.Message {
+	return models.Message{
+		ID:             primitive.NewObjectID(),
+		ConversationID: conversation.ID,
+		ParentID:       parent,
+		Role:           role,
+		Content:        content,
+		Language:       lang,
+		CreatedAt:      time.Now(),
 	}
+}
 
-	conversation.Messages = append(conversation.Messages, userMessage)
+// appendMessages stores msgs, makes the last one the active leaf and
+// atomically bumps the conversation's message count and version. The
+// active leaf only moves if it is still the one conversation was read
+// with; when another turn moved it first, the messages are removed again
+// and errConversationConflict is returned instead of silently forking.
+func appendMessages(conversation *models.Conversation, msgs ...models.Message) error {
+	docs := make([]any, len(msgs))
+	ids := make([]primitive.ObjectID, len(msgs))
+	for i, m := range msgs {
+		docs[i] = m
+		ids[i] = m.ID
+	}
+	messages := database.GetCollection("messages")
+	if _, err := messages.InsertMany(context.Background(), docs); err != nil {
+		return err
+	}
 
-	// Build messages for Groq from conversation history
-	msgs := services.BuildChatMessages(conversation.Messages, req.Language)
-	aiResponse, err := groqService.Chat(context.Background(), msgs)
+	var updated models.Conversation
+	err := database.GetCollection("conversations").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": conversation.ID, "activeLeafId": conversation.ActiveLeafID},
+		bson.M{
+			"$inc": bson.M{"messageCount": len(msgs), "version": 1},
+			"$set": bson.M{
+				"activeLeafId": msgs[len(msgs)-1].ID,
+				"lastMessage":  utils.Preview(msgs[len(msgs)-1].Content),
+				"updatedAt":    time.Now(),
+			},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		if _, err := messages.DeleteMany(context.Background(), bson.M{"_id": bson.M{"$in": ids}}); err != nil {
+			log.Printf("Chat: failed to remove unsaved turn of conversation %s: %v", conversation.ID.Hex(), err)
+		}
+		return errConversationConflict
+	}
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "AI service error: "+err.Error())
+		return err
 	}
+	updated.Messages = conversation.Messages
+	*conversation = updated
+	return nil
+}
 
-	// Emergency detection and disclaimer handling
-	finalContent := aiResponse
-	if detectEmergency(req.Message) {
-		finalContent = "Emergency warning: Your symptoms may be serious. Please seek immediate medical attention or contact local emergency services immediately.\n\n" + finalContent
+// updateConversation applies set to the conversation only if nobody else
+// has written it since it was read, returning errConversationConflict
+// otherwise.
+func updateConversation(conversation *models.Conversation, set bson.M) error {
+	set["updatedAt"] = time.Now()
+	var updated models.Conversation
+	err := database.GetCollection("conversations").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": conversation.ID, "version": conversation.Version},
+		bson.M{"$set": set, "$inc": bson.M{"version": 1}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&updated)
+	if err == mongo.ErrNoDocuments {
+		return errConversationConflict
+	}
+	if err != nil {
+		return err
+	}
+	updated.Messages = conversation.Messages
+	*conversation = updated
+	return nil
+}
+
+// chatPrompt builds the model prompt from path, the branch being answered,
+// and reports which persona prompt version it used and the knowledge base
+// passages it included. The conversation summary replaces the turns it
+// covers when path contains them; when the rest no longer fits the model's
+// token budget, older turns are folded into a new summary. Bridged answers
+// are prompted entirely in English.
+func chatPrompt(ctx context.Context, conversation *models.Conversation, path []models.Message, opts answerOptions) (services.ChatPrompt, models.PromptRef, []models.Citation) {
+	bridge := usesBridge(opts.Lang)
+	if bridge {
+		opts.Lang = "en"
+	}
+	system, ref := systemPrompt(conversation, opts)
+	prompt := services.ChatPrompt{
+		TargetLang: opts.Lang,
+		History:    path,
+		Mode:       opts.Mode,
+		System:     system,
+		Bridge:     bridge,
+	}
+	query := path[len(path)-1].Content
+	if english := path[len(path)-1].EnglishContent; bridge && english != "" {
+		query = english
+	}
+	passages, citations, err := searchKnowledge(ctx, query, knowledgeTopK())
+	if err != nil {
+		// Answer without the knowledge base rather than not at all
+		log.Printf("Chat: knowledge search failed for conversation %s: %v", conversation.ID.Hex(), err)
+	}
+	prompt.Passages = passages
+	if conversation.SummarizedThrough != nil {
+		for i, m := range path {
+			if m.ID == *conversation.SummarizedThrough {
+				prompt.Summary = conversation.Summary
+				prompt.History = path[i+1:]
+				break
+			}
+		}
 	}
-	finalContent = finalContent
 
-	// Add AI response to conversation
-	assistantMessage := models.Message{
-		ID:        primitive.NewObjectID(),
-		Role:      "assistant",
-		Content:   finalContent,
-		Language:  req.Language,
-		CreatedAt: time.Now(),
+	budget := services.ContextBudget(services.LLM().Model())
+	if services.PromptTokens(services.BuildChatMessages(prompt)) <= budget {
+		return prompt, ref, citations
 	}
 
-	conversation.Messages = append(conversation.Messages, assistantMessage)
-	conversation.UpdatedAt = time.Now()
+	older, recent := services.SplitHistory(prompt.History, services.RecentBudget(budget))
+	prompt.History = recent
+	if len(older) == 0 {
+		return prompt, ref, citations
+	}
 
-	// Save updated conversation
-	_, err = conversationCollection.UpdateOne(
-		context.Background(),
-		bson.M{"_id": conversation.ID},
-		bson.M{"$set": conversation},
-	)
+	summary, err := services.LLM().Summarize(ctx, prompt.Summary, older)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save conversation")
+		// Fall back to plain truncation; the summary is retried next turn
+		log.Printf("Chat: summarization failed for conversation %s: %v", conversation.ID.Hex(), err)
+		return prompt, ref, citations
 	}
+	prompt.Summary = summary
 
-	return c.JSON(models.ChatResponse{
-		ConversationID: conversation.ID.Hex(),
-		Message:        assistantMessage,
-		Conversation:   &conversation,
+	err = updateConversation(conversation, bson.M{
+		"summary":           summary,
+		"summarizedThrough": older[len(older)-1].ID,
 	})
+	if err != nil {
+		// Another turn got there first; this prompt still uses the new summary
+		log.Printf("Chat: failed to save summary for conversation %s: %v", conversation.ID.Hex(), err)
+	}
+	return prompt, ref, citations
 }
 
-func GetConversations(c *fiber.Ctx) error {
+// replyTo asks the model to answer the user message at the end of path and
+// returns the assistant message (not yet saved) with the emergency
+// assessment of the question. Flagged answers start with a warning. In
+// triage mode the parsed answer and the raw model output are kept on the
+// message; if the model never produces valid JSON the raw text is used.
+// In text mode the model may call the chat tools, which are recorded as
+// message parts. Knowledge base passages the answer cites are attached.
+// Bridged answers are written in English and translated into opts.Lang,
+// keeping the English on the message (a bridged triage answer also has its
+// fields translated); the question's English version is
+// filled in on path if it is missing. The tokens spent, including on
+// summaries and classification, are recorded in the answer's Usage and
+// charged to the user straight away, whether or not the answer is saved.
+func replyTo(conversation *models.Conversation, path []models.Message, opts answerOptions) (models.Message, models.EmergencyAssessment, error) {
+	ctx, usage := services.WithUsage(withAccountName(context.Background(), conversation, &opts))
+	bridgeQuestion(ctx, &path[len(path)-1], opts.Lang)
+	question := path[len(path)-1]
+	assessment := assessEmergency(ctx, question.Content)
+
+	prompt, ref, citations := chatPrompt(ctx, conversation, path, opts)
+	msgs := services.BuildChatMessages(prompt)
+	var (
+		aiResponse string
+		raw        string
+		triage     *models.Triage
+		parts      []models.MessagePart
+		err        error
+	)
+	if opts.Mode == models.ChatModeTriage {
+		raw, triage, err = services.LLM().Triage(ctx, msgs)
+		if errors.Is(err, services.ErrInvalidTriage) {
+			log.Printf("Chat: triage output still invalid after repair for conversation %s: %v", conversation.ID.Hex(), err)
+			err = nil
+		}
+		aiResponse = raw
+		if triage != nil {
+			aiResponse = services.RenderTriage(triage)
+		}
+	} else if chatToolsEnabled() {
+		aiResponse, parts, err = services.LLM().ChatWithTools(ctx, msgs, chatTools(conversation), chatToolIterations())
+	} else {
+		aiResponse, err = services.LLM().Chat(ctx, msgs)
+	}
+	if err != nil {
+		recordUsage(conversation, nil, usage.Total())
+		return models.Message{}, assessment, err
+	}
+
+	reply := newMessage(conversation, &question.ID, "assistant", "", opts.Lang)
+	reply.Citations = citedSources(aiResponse, citations)
+	if usesBridge(opts.Lang) {
+		reply.EnglishContent = aiResponse
+		if triage != nil {
+			triage, aiResponse = bridgeTriage(ctx, triage, opts.Lang)
+		} else {
+			aiResponse = bridgeAnswer(ctx, aiResponse, opts.Lang)
+		}
+	}
+	reply.Content = services.EmergencyNotice(assessment.Severity, opts.Lang) + aiResponse
+	reply.Prompt = &ref
+	reply.Usage = usage.Total()
+	recordUsage(conversation, &reply.ID, reply.Usage)
+	reply.Triage = triage
+	reply.RawOutput = raw
+	if len(parts) > 1 {
+		// Plain answers carry no parts; only keep the trail of tool calls
+		reply.Parts = parts
+	}
+	flagMessage(&reply, assessment)
+	return reply, assessment, nil
+}
+
+func Chat(c *fiber.Ctx) error {
+
+	if services.LLM() == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Chat is not configured")
+	}
+	var req models.ChatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if verdict := moderateInput(c, req.Message); verdict.Flagged {
+		return refuseInput(c, verdict)
+	}
+
 	userID := c.Locals("userId").(string)
 	userObjID, _ := primitive.ObjectIDFromHex(userID)
 
-	collection := database.GetCollection("conversations")
-	cursor, err := collection.Find(context.Background(), bson.M{"userId": userObjID})
+	// Get or create conversation
+	conversation, status, err := chatConversation(userObjID, req)
 	if err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch conversations")
+		return utils.ErrorResponse(c, status, err.Error())
 	}
-	defer cursor.Close(context.Background())
 
-	var conversations []models.Conversation
-	if err := cursor.All(context.Background(), &conversations); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode conversations")
+	path, err := activeTurnPath(conversation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	// Continue the active branch
+	userMessage := newMessage(conversation, conversation.ActiveLeafID, "user", req.Message, req.Language)
+	turn := append(path, userMessage)
+	assistantMessage, assessment, err := replyTo(conversation, turn, answerOptions{
+		Lang:   req.Language,
+		Mode:   req.Mode,
+		Locale: requestLocale(c, req.Locale),
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "AI service error: "+err.Error())
+	}
+	userMessage = turn[len(turn)-1] // with its English version, if bridged
+	flagMessage(&userMessage, assessment)
+
+	// Save both turns
+	err = appendMessages(conversation, userMessage, assistantMessage)
+	if err == errConversationConflict {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Conversation was modified; reload and try again")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save conversation")
 	}
+	recordEmergency(conversation, userMessage, assessment)
 
-	return c.JSON(fiber.Map{
-		"conversations": conversations,
+	return c.JSON(models.ChatResponse{
+		ConversationID: conversation.ID.Hex(),
+		Message:        assistantMessage,
+		Conversation:   conversation,
 	})
 }
 
-func GetConversation(c *fiber.Ctx) error {
-	conversationID := c.Params("id")
-	convObjID, err := primitive.ObjectIDFromHex(conversationID)
+// GetMessageAudio speaks a stored chat message in the message's language.
+func GetMessageAudio(c *fiber.Ctx) error {
+	convObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid conversation ID")
 	}
+	msgObjID, err := primitive.ObjectIDFromHex(c.Params("msgId"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid message ID")
+	}
 
 	userID := c.Locals("userId").(string)
 	userObjID, _ := primitive.ObjectIDFromHex(userID)
 
 	collection := database.GetCollection("conversations")
-	var conversation models.Conversation
-	err = collection.FindOne(context.Background(), bson.M{"_id": convObjID, "userId": userObjID}).Decode(&conversation)
+	err = collection.FindOne(context.Background(), bson.M{"_id": convObjID, "userId": userObjID, "deletedAt": nil}).Err()
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusNotFound, "Conversation not found")
 	}
 
-	return c.JSON(fiber.Map{
-		"conversation": conversation,
-	})
-}
-
-// detectEmergency performs a simple keyword check for emergency symptoms.
-func detectEmergency(text string) bool {
-	lower := strings.ToLower(text)
-	keywords := []string{
-		"chest pain",
-		"difficulty breathing",
-		"shortness of breath",
-		"severe bleeding",
-		"unconscious",
-		"fainting",
-		"stroke",
-		"heart attack",
-		"suicidal",
-		"overdose",
-	}
-	for _, k := range keywords {
-		if strings.Contains(lower, k) {
-			return true
-		}
+	var m models.Message
+	err = database.GetCollection("messages").FindOne(context.Background(), bson.M{"_id": msgObjID, "conversationId": convObjID}).Decode(&m)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Message not found")
 	}
-	return false
+
+	return sendSpeech(c, m.Content, normalizeLang(m.Language), c.Query("format"), c.QueryInt("sampleRate"), storedAudioCache)
 }
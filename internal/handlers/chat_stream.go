@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// writeSSE writes one Server-Sent Event and flushes it. A flush error means
+// the client has gone away.
+func writeSSE(w *bufio.Writer, event string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	return w.Flush()
+}
+
+// ChatStream is Chat over Server-Sent Events. Events:
+//
+//	start  {conversationId, userMessageId, severity}
+//	token  {content}
+//	error  {error}
+//	done   {conversationId, messageId, partial}
+//
+// The assistant message is saved once the model finishes, or with
+// partial=true if the client disconnects or the stream fails midway.
+// Bridged answers (see usesBridge) are translated before sending, so they
+// arrive as a single token event.
+func ChatStream(c *fiber.Ctx) error {
+	if services.LLM() == nil {
+		return utils.ErrorResponse(c, fiber.StatusServiceUnavailable, "Chat is not configured")
+	}
+	var req models.ChatRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if req.Mode == models.ChatModeTriage {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Triage mode is not available for streaming; use /chat")
+	}
+	if verdict := moderateInput(c, req.Message); verdict.Flagged {
+		return refuseInput(c, verdict)
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	conversation, status, err := chatConversation(userObjID, req)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	path, err := activeTurnPath(conversation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	userMessage := newMessage(conversation, conversation.ActiveLeafID, "user", req.Message, req.Language)
+	bridge := usesBridge(req.Language)
+	opts := answerOptions{
+		Lang:   req.Language,
+		Mode:   models.ChatModeText,
+		Locale: requestLocale(c, req.Locale),
+	}
+	usageCtx, usage := services.WithUsage(withAccountName(context.Background(), conversation, &opts))
+	bridgeQuestion(usageCtx, &userMessage, req.Language)
+	prompt, promptRef, citations := chatPrompt(usageCtx, conversation, append(path, userMessage), opts)
+	msgs := services.BuildChatMessages(prompt)
+
+	assessment := assessEmergency(usageCtx, req.Message)
+	flagMessage(&userMessage, assessment)
+	prefix := services.EmergencyNotice(assessment.Severity, req.Language)
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no") // stop nginx from buffering the stream
+
+	// The writer runs after this handler returns, so it must not touch c.
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithTimeout(usageCtx, 2*time.Minute)
+		defer cancel()
+
+		disconnected := false
+		send := func(event string, data any) error {
+			if disconnected {
+				return context.Canceled
+			}
+			if err := writeSSE(w, event, data); err != nil {
+				disconnected = true
+				cancel()
+				return err
+			}
+			return nil
+		}
+
+		send("start", fiber.Map{
+			"conversationId": conversation.ID.Hex(),
+			"userMessageId":  userMessage.ID.Hex(),
+			"severity":       assessment.Severity,
+		})
+		if prefix != "" {
+			send("token", fiber.Map{"content": prefix})
+		}
+
+		var (
+			aiResponse string
+			english    string
+			streamErr  error
+		)
+		if bridge {
+			// The translation needs the whole answer, so it arrives in one piece
+			english, streamErr = services.LLM().Chat(ctx, msgs)
+			if streamErr == nil {
+				aiResponse = bridgeAnswer(ctx, english, req.Language)
+				send("token", fiber.Map{"content": aiResponse})
+			}
+		} else {
+			aiResponse, streamErr = services.LLM().ChatStream(ctx, msgs, func(delta string) error {
+				return send("token", fiber.Map{"content": delta})
+			})
+		}
+		if streamErr != nil && !disconnected {
+			send("error", fiber.Map{"error": "AI service error: " + streamErr.Error()})
+		}
+
+		partial := disconnected || streamErr != nil
+		if partial && aiResponse == "" {
+			// Nothing worth keeping; still record the user's turn
+			recordUsage(conversation, nil, usage.Total())
+			if err := appendMessages(conversation, userMessage); err != nil {
+				log.Printf("ChatStream: failed to save conversation %s: %v", conversation.ID.Hex(), err)
+				return
+			}
+			recordEmergency(conversation, userMessage, assessment)
+			return
+		}
+
+		assistantMessage := newMessage(conversation, &userMessage.ID, "assistant", prefix+aiResponse, req.Language)
+		assistantMessage.Partial = partial
+		assistantMessage.Prompt = &promptRef
+		assistantMessage.Usage = usage.Total()
+		recordUsage(conversation, &assistantMessage.ID, assistantMessage.Usage)
+		assistantMessage.EnglishContent = english
+		if bridge {
+			assistantMessage.Citations = citedSources(english, citations)
+		} else {
+			assistantMessage.Citations = citedSources(aiResponse, citations)
+		}
+		flagMessage(&assistantMessage, assessment)
+		if err := appendMessages(conversation, userMessage, assistantMessage); err != nil {
+			log.Printf("ChatStream: failed to save conversation %s: %v", conversation.ID.Hex(), err)
+			if err == errConversationConflict {
+				send("error", fiber.Map{"error": "Conversation was modified; reload and try again"})
+			} else {
+				send("error", fiber.Map{"error": "Failed to save conversation"})
+			}
+			return
+		}
+		recordEmergency(conversation, userMessage, assessment)
+
+		send("done", fiber.Map{
+			"conversationId": conversation.ID.Hex(),
+			"messageId":      assistantMessage.ID.Hex(),
+			"partial":        partial,
+		})
+	})
+	return nil
+}
@@ -0,0 +1,257 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// chatToolsEnabled reports whether chat answers may call tools
+// (CHAT_TOOLS=off disables them).
+func chatToolsEnabled() bool {
+	return !strings.EqualFold(strings.TrimSpace(os.Getenv("CHAT_TOOLS")), "off")
+}
+
+// chatToolIterations reads CHAT_TOOL_MAX_ITERATIONS; 0 means the default.
+func chatToolIterations() int {
+	n, _ := strconv.Atoi(strings.TrimSpace(os.Getenv("CHAT_TOOL_MAX_ITERATIONS")))
+	return n
+}
+
+var languageParam = map[string]any{
+	"type":        "string",
+	"description": "Language code: en, yo (Yoruba), ig (Igbo) or ha (Hausa)",
+}
+
+// chatTools are the functions the chat model may call for conversation.
+func chatTools(conversation *models.Conversation) []services.Tool {
+	return []services.Tool{
+		{
+			Name:        "translate",
+			Description: "Translate text between English, Yoruba, Igbo and Hausa with the app's translation service. Use this instead of translating from memory.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"text":       map[string]any{"type": "string", "description": "Text to translate"},
+					"sourceLang": languageParam,
+					"targetLang": languageParam,
+				},
+				"required": []string{"text", "sourceLang", "targetLang"},
+			},
+			Run: toolTranslate,
+		},
+		{
+			Name:        "lookup_glossary",
+			Description: "Look up a word or short phrase in the app's glossary: pronunciation guidance and translations that this user has rated or corrected.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"term":     map[string]any{"type": "string", "description": "Word or phrase to look up"},
+					"language": languageParam,
+				},
+				"required": []string{"term"},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (any, error) {
+				return toolLookupGlossary(ctx, conversation.UserID, args)
+			},
+		},
+		{
+			Name:        "generate_audio",
+			Description: "Speak text aloud in a language and return a link the user can play to hear the pronunciation.",
+			Parameters: map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"text":     map[string]any{"type": "string", "description": "Text to speak"},
+					"language": languageParam,
+				},
+				"required": []string{"text", "language"},
+			},
+			Run: func(ctx context.Context, args json.RawMessage) (any, error) {
+				return toolGenerateAudio(conversation.UserID, args)
+			},
+		},
+	}
+}
+
+func toolTranslate(ctx context.Context, args json.RawMessage) (any, error) {
+	var in struct {
+		Text       string `json:"text"`
+		SourceLang string `json:"sourceLang"`
+		TargetLang string `json:"targetLang"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil || in.Text == "" || in.TargetLang == "" {
+		return nil, fmt.Errorf("text, sourceLang and targetLang are required")
+	}
+	if in.SourceLang == "" {
+		in.SourceLang = "en"
+	}
+
+	translated, err := services.TranslateText(ctx, in.Text, in.SourceLang, in.TargetLang)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"translation": translated}, nil
+}
+
+// toolLookupGlossary searches the curated lexicon and the caller's own
+// rated translations; other users' translations are never shown.
+func toolLookupGlossary(ctx context.Context, userID primitive.ObjectID, args json.RawMessage) (any, error) {
+	var in struct {
+		Term     string `json:"term"`
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil || strings.TrimSpace(in.Term) == "" {
+		return nil, fmt.Errorf("term is required")
+	}
+	term := regexp.QuoteMeta(strings.TrimSpace(in.Term))
+	exact := bson.M{"$regex": "^" + term + "$", "$options": "i"}
+
+	lexFilter := bson.M{"grapheme": exact}
+	if in.Language != "" {
+		lexFilter["language"] = baseLang(in.Language)
+	}
+	var pronunciations []models.LexiconEntry
+	cursor, err := database.GetCollection("lexicon").Find(ctx, lexFilter, options.Find().SetLimit(10))
+	if err != nil {
+		return nil, err
+	}
+	if err := cursor.All(ctx, &pronunciations); err != nil {
+		return nil, err
+	}
+
+	// Translations of the term that the user rated well or corrected
+	pipeline := []bson.M{
+		{"$match": bson.M{"userId": userID, "$or": []bson.M{{"sourceText": exact}, {"translatedText": exact}}}},
+		{"$lookup": bson.M{"from": "feedbacks", "localField": "_id", "foreignField": "translationId", "as": "feedback"}},
+		{"$unwind": "$feedback"},
+		{"$match": bson.M{"feedback.userId": userID, "$or": []bson.M{
+			{"feedback.rating": bson.M{"$gte": 4}},
+			{"feedback.suggestedText": bson.M{"$nin": []any{nil, ""}}},
+		}}},
+		{"$limit": 10},
+	}
+	cursor, err = database.GetCollection("translations").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var rated []struct {
+		models.Translation `bson:",inline"`
+		Feedback           models.Feedback `bson:"feedback"`
+	}
+	if err := cursor.All(ctx, &rated); err != nil {
+		return nil, err
+	}
+
+	type entry struct {
+		Source     string `json:"source"`
+		SourceLang string `json:"sourceLang"`
+		Target     string `json:"target"`
+		TargetLang string `json:"targetLang"`
+		Rating     int    `json:"rating"`
+		Correction string `json:"correction,omitempty"`
+	}
+	translations := []entry{}
+	for _, r := range rated {
+		translations = append(translations, entry{
+			Source:     r.SourceText,
+			SourceLang: r.SourceLang,
+			Target:     r.TranslatedText,
+			TargetLang: r.TargetLang,
+			Rating:     r.Feedback.Rating,
+			Correction: r.Feedback.SuggestedText,
+		})
+	}
+
+	type pron struct {
+		Language   string `json:"language"`
+		Word       string `json:"word"`
+		Respelling string `json:"respelling,omitempty"`
+		IPA        string `json:"ipa,omitempty"`
+		Note       string `json:"note,omitempty"`
+	}
+	prons := []pron{}
+	for _, p := range pronunciations {
+		prons = append(prons, pron{p.Language, p.Grapheme, p.Respelling, p.IPA, p.Note})
+	}
+
+	if len(prons) == 0 && len(translations) == 0 {
+		return map[string]any{"found": false}, nil
+	}
+	return map[string]any{
+		"found":          true,
+		"pronunciations": prons,
+		"translations":   translations,
+	}, nil
+}
+
+func toolGenerateAudio(userID primitive.ObjectID, args json.RawMessage) (any, error) {
+	var in struct {
+		Text     string `json:"text"`
+		Language string `json:"language"`
+	}
+	if err := json.Unmarshal(args, &in); err != nil || strings.TrimSpace(in.Text) == "" || in.Language == "" {
+		return nil, fmt.Errorf("text and language are required")
+	}
+	lang := normalizeLang(in.Language)
+
+	doc, err := services.ParseSpeech(in.Text)
+	if err != nil {
+		return nil, err
+	}
+	audioBytes, ctype, _, err := speak(doc, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	clip := models.AudioClip{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		ContentType: ctype,
+		Data:        audioBytes,
+		Language:    lang,
+		Text:        in.Text,
+		CreatedAt:   time.Now(),
+	}
+	if _, err := database.GetCollection("audio_clips").InsertOne(context.Background(), clip); err != nil {
+		return nil, fmt.Errorf("save audio: %w", err)
+	}
+	return map[string]string{
+		"audioId":  clip.ID.Hex(),
+		"audioUrl": "/api/v1/clips/" + clip.ID.Hex() + "/audio",
+	}, nil
+}
+
+// GetAudioClip plays back a stored audio clip, such as one generated by a
+// chat tool call.
+func GetAudioClip(c *fiber.Ctx) error {
+	clipObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid clip ID")
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	var clip models.AudioClip
+	err = database.GetCollection("audio_clips").FindOne(context.Background(), bson.M{"_id": clipObjID, "userId": userObjID}).Decode(&clip)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Audio clip not found")
+	}
+
+	return sendAudio(c, clip.Data, clip.ContentType, c.Query("format"), c.QueryInt("sampleRate"), storedAudioCache)
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// encodeCursor makes an opaque pagination cursor from a sort time and ID.
+func encodeCursor(t time.Time, id primitive.ObjectID) string {
+	raw := strconv.FormatInt(t.UnixMilli(), 10) + "." + id.Hex()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeCursor reverses encodeCursor.
+func decodeCursor(cursor string) (time.Time, primitive.ObjectID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("Invalid cursor")
+	}
+	millis, hex, ok := strings.Cut(string(raw), ".")
+	if !ok {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("Invalid cursor")
+	}
+	ms, err := strconv.ParseInt(millis, 10, 64)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("Invalid cursor")
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, fmt.Errorf("Invalid cursor")
+	}
+	return time.UnixMilli(ms), id, nil
+}
+
+// beforeCursor limits a newest-first query on field to documents after
+// cursor in that order.
+func beforeCursor(filter bson.M, field, cursor string) error {
+	t, id, err := decodeCursor(cursor)
+	if err != nil {
+		return err
+	}
+	filter["$or"] = []bson.M{
+		{field: bson.M{"$lt": t}},
+		{field: t, "_id": bson.M{"$lt": id}},
+	}
+	return nil
+}
+
+// pageLimit reads ?limit= the same way the admin lists do.
+func pageLimit(c *fiber.Ctx, def int) int {
+	limit, err := strconv.Atoi(c.Query("limit", strconv.Itoa(def)))
+	if err != nil || limit < 1 || limit > 100 {
+		limit = def
+	}
+	return limit
+}
+
+// ownConversation loads the conversation in :id if it belongs to the
+// caller. Trashed conversations are only found when deleted is true.
+func ownConversation(c *fiber.Ctx, deleted bool) (*models.Conversation, int, error) {
+	convObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, fmt.Errorf("Invalid conversation ID")
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	filter := bson.M{"_id": convObjID, "userId": userObjID, "deletedAt": nil}
+	if deleted {
+		filter["deletedAt"] = bson.M{"$ne": nil}
+	}
+
+	var conversation models.Conversation
+	err = database.GetCollection("conversations").FindOne(context.Background(), filter).Decode(&conversation)
+	if err != nil {
+		return nil, fiber.StatusNotFound, fmt.Errorf("Conversation not found")
+	}
+	return &conversation, fiber.StatusOK, nil
+}
+
+// messagePage returns up to limit messages of the active branch older than
+// the before cursor, oldest first, and the cursor for the page before them
+// ("" if none). The cursor names the oldest message already returned, so
+// each page only walks its own part of the branch.
+func messagePage(conversation *models.Conversation, before string, limit int) ([]models.Message, string, error) {
+	if conversation.ActiveLeafID == nil {
+		return []models.Message{}, "", nil
+	}
+	from := *conversation.ActiveLeafID
+	skip := false
+	if before != "" {
+		_, id, err := decodeCursor(before)
+		if err != nil {
+			return nil, "", err
+		}
+		from, skip = id, true
+	}
+
+	page := []models.Message{}
+	err := walkPath(conversation.ID, from, func(m models.Message) bool {
+		if skip {
+			skip = false
+			return true
+		}
+		page = append(page, m)
+		return len(page) <= limit
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(page) > limit {
+		page = page[:limit]
+		next = encodeCursor(page[limit-1].CreatedAt, page[limit-1].ID)
+	}
+	slices.Reverse(page)
+	if err := withSiblings(conversation.ID, page); err != nil {
+		return nil, "", err
+	}
+	return page, next, nil
+}
+
+// GetConversations lists the caller's conversations, most recently updated
+// first, as lightweight summaries. Query: limit, cursor, pinned=true,
+// archived=true (archived only; excluded by default), deleted=true (trash).
+func GetConversations(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	limit := pageLimit(c, 20)
+
+	filter := bson.M{"userId": userObjID, "deletedAt": nil}
+	if c.QueryBool("deleted") {
+		filter["deletedAt"] = bson.M{"$ne": nil}
+	} else if c.QueryBool("archived") {
+		filter["archived"] = true
+	} else {
+		filter["archived"] = bson.M{"$ne": true}
+	}
+	if c.QueryBool("pinned") {
+		filter["pinned"] = true
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		if err := beforeCursor(filter, "updatedAt", cursor); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	collection := database.GetCollection("conversations")
+	opts := options.Find().
+		SetSort(bson.D{{Key: "updatedAt", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch conversations")
+	}
+	defer cursor.Close(context.Background())
+
+	conversations := []models.ConversationSummary{}
+	if err := cursor.All(context.Background(), &conversations); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode conversations")
+	}
+
+	next := ""
+	if len(conversations) > limit {
+		conversations = conversations[:limit]
+		last := conversations[limit-1]
+		next = encodeCursor(last.UpdatedAt, last.ID)
+	}
+
+	return c.JSON(fiber.Map{
+		"conversations": conversations,
+		"nextCursor":    next,
+	})
+}
+
+// GetConversation returns a conversation with its newest page of messages;
+// older pages come from GetConversationMessages with ?before=nextCursor.
+func GetConversation(c *fiber.Ctx) error {
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	messages, next, err := messagePage(conversation, "", pageLimit(c, 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+	conversation.Messages = messages
+
+	return c.JSON(fiber.Map{
+		"conversation": conversation,
+		"nextCursor":   next,
+	})
+}
+
+// GetConversationMessages pages backwards through a conversation's messages.
+func GetConversationMessages(c *fiber.Ctx) error {
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	before := c.Query("before")
+	if before != "" {
+		if _, _, err := decodeCursor(before); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+		}
+	}
+
+	messages, next, err := messagePage(conversation, before, pageLimit(c, 50))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	return c.JSON(fiber.Map{
+		"messages":   messages,
+		"nextCursor": next,
+	})
+}
+
+// UpdateConversation renames, pins or archives a conversation.
+func UpdateConversation(c *fiber.Ctx) error {
+	var req models.ConversationUpdateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	set := bson.M{}
+	if req.Title != nil {
+		title := strings.TrimSpace(*req.Title)
+		if title == "" {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Title cannot be empty")
+		}
+		set["title"] = title
+	}
+	if req.Pinned != nil {
+		set["pinned"] = *req.Pinned
+	}
+	if req.Archived != nil {
+		set["archived"] = *req.Archived
+	}
+	if len(set) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Nothing to update")
+	}
+	if req.Version != nil {
+		conversation.Version = *req.Version
+	}
+
+	err = updateConversation(conversation, set)
+	if err == errConversationConflict {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Conversation was modified; reload and try again")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update conversation")
+	}
+
+	return c.JSON(fiber.Map{
+		"conversation": conversation,
+	})
+}
+
+// DeleteConversation moves a conversation to the trash.
+func DeleteConversation(c *fiber.Ctx) error {
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	now := time.Now()
+	_, err = database.GetCollection("conversations").UpdateOne(
+		context.Background(),
+		bson.M{"_id": conversation.ID},
+		bson.M{"$set": bson.M{"deletedAt": now, "updatedAt": now}, "$inc": bson.M{"version": 1}},
+	)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete conversation")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// RestoreConversation takes a conversation back out of the trash.
+func RestoreConversation(c *fiber.Ctx) error {
+	conversation, status, err := ownConversation(c, true)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	var restored models.Conversation
+	err = database.GetCollection("conversations").FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": conversation.ID},
+		bson.M{
+			"$unset": bson.M{"deletedAt": ""},
+			"$set":   bson.M{"updatedAt": time.Now()},
+			"$inc":   bson.M{"version": 1},
+		},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&restored)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to restore conversation")
+	}
+
+	return c.JSON(fiber.Map{
+		"conversation": restored,
+	})
+}
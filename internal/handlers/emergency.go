@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+)
+
+var (
+	emergencyOnce     sync.Once
+	emergencyDetector *services.EmergencyDetector
+)
+
+// assessEmergency runs the red-flag lists over text and, when
+// EMERGENCY_CLASSIFIER=llm, a model triage pass as well.
+func assessEmergency(ctx context.Context, text string) models.EmergencyAssessment {
+	emergencyOnce.Do(func() {
+		rules, err := services.LoadEmergencyRules()
+		if err != nil {
+			log.Printf("Emergency: %v; using built-in rules", err)
+			rules = services.DefaultEmergencyRules
+		}
+		emergencyDetector = services.NewEmergencyDetector(rules)
+	})
+
+	assessment := emergencyDetector.Detect(text)
+	if llm := services.LLM(); llm != nil && strings.EqualFold(strings.TrimSpace(os.Getenv("EMERGENCY_CLASSIFIER")), "llm") {
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+		defer cancel()
+		verdict, err := llm.ClassifyEmergency(ctx, text)
+		if err != nil {
+			log.Printf("Emergency: classifier failed: %v", err)
+		} else {
+			assessment = services.MergeAssessments(assessment, verdict)
+		}
+	}
+	return assessment
+}
+
+// flagMessage shows the assessment's severity on a message.
+func flagMessage(m *models.Message, assessment models.EmergencyAssessment) {
+	m.Severity = assessment.Severity
+	m.EmergencyCategory = assessment.Category
+}
+
+// recordEmergency stores an emergency event for a flagged user message and
+// escalates it to the webhook in the background when severe enough.
+func recordEmergency(conversation *models.Conversation, message models.Message, assessment models.EmergencyAssessment) {
+	if assessment.Severity == models.SeverityNone {
+		return
+	}
+
+	event := models.EmergencyEvent{
+		ID:                  primitive.NewObjectID(),
+		UserID:              conversation.UserID,
+		ConversationID:      conversation.ID,
+		MessageID:           message.ID,
+		Text:                message.Content,
+		Language:            message.Language,
+		EmergencyAssessment: assessment,
+		CreatedAt:           time.Now(),
+	}
+	collection := database.GetCollection("emergency_events")
+	if _, err := collection.InsertOne(context.Background(), event); err != nil {
+		log.Printf("Emergency: failed to record event for message %s: %v", message.ID.Hex(), err)
+		return
+	}
+
+	if !services.EscalationConfigured() ||
+		models.SeverityRank(assessment.Severity) < models.SeverityRank(services.EscalationMinSeverity()) {
+		return
+	}
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+
+		status := "sent"
+		if err := services.Escalate(ctx, event); err != nil {
+			log.Printf("Emergency: webhook failed for event %s: %v", event.ID.Hex(), err)
+			status = "failed"
+		}
+		_, err := collection.UpdateOne(context.Background(),
+			bson.M{"_id": event.ID},
+			bson.M{"$set": bson.M{"webhookStatus": status}},
+		)
+		if err != nil {
+			log.Printf("Emergency: failed to update event %s: %v", event.ID.Hex(), err)
+		}
+	}()
+}
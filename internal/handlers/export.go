@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// exportOptions reads ?format= (md, pdf or json, default def) and ?tz= (an
+// IANA zone such as Africa/Lagos, default UTC).
+func exportOptions(c *fiber.Ctx, def string) (string, *time.Location, error) {
+	format := c.Query("format", def)
+	if format != "md" && format != "pdf" && format != "json" {
+		return "", nil, fmt.Errorf("format must be md, pdf or json")
+	}
+	loc := time.UTC
+	if tz := c.Query("tz"); tz != "" {
+		l, err := time.LoadLocation(tz)
+		if err != nil {
+			return "", nil, fmt.Errorf("Unknown time zone")
+		}
+		loc = l
+	}
+	return format, loc, nil
+}
+
+// exportLanguage is ?lang=, defaulting to the language of the latest
+// message.
+func exportLanguage(c *fiber.Ctx, path []models.Message) string {
+	lang := c.Query("lang")
+	if lang == "" && len(path) > 0 {
+		lang = path[len(path)-1].Language
+	}
+	return lang
+}
+
+// sendExport writes export in format, as a download when disposition is
+// "attachment" or for display when it is "inline".
+func sendExport(c *fiber.Ctx, export models.ConversationExport, format string, loc *time.Location, disposition string) error {
+	filename := fmt.Sprintf("conversation-%s-%s.%s", export.ID.Hex(), export.ExportedAt.In(loc).Format("20060102"), format)
+	c.Set("Content-Disposition", fmt.Sprintf(`%s; filename="%s"`, disposition, filename))
+	c.Set("Cache-Control", "no-store")
+
+	switch format {
+	case "pdf":
+		data, err := services.ExportPDF(export, loc)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to render PDF")
+		}
+		c.Set("Content-Type", "application/pdf")
+		return c.Send(data)
+	case "json":
+		return c.JSON(export)
+	}
+	c.Set("Content-Type", "text/markdown; charset=utf-8")
+	return c.Send(services.ExportMarkdown(export, loc))
+}
+
+// ExportConversation downloads the active branch of a conversation as
+// ?format=md (default), pdf or json. Times are shown in ?tz= and the
+// disclaimer is in ?lang=, defaulting to the latest message's language.
+func ExportConversation(c *fiber.Ctx) error {
+	format, loc, err := exportOptions(c, "md")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+	path, err := activePath(conversation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	export := services.NewConversationExport(conversation, path, exportLanguage(c, path))
+	return sendExport(c, export, format, loc, "attachment")
+}
@@ -0,0 +1,538 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/contrib/websocket"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// maxInterpreterParticipants keeps sessions to the doctor/patient pair.
+const maxInterpreterParticipants = 2
+
+// maxInterpreterMessage bounds one websocket frame, enough for a spoken
+// turn sent as base64 audio.
+const maxInterpreterMessage = 10 << 20
+
+// interpreterInbound is a message from a participant. Audio is base64 in
+// the speaker's language; binary frames are treated as audio too.
+type interpreterInbound struct {
+	Type     string `json:"type"` // "text" or "audio"
+	Text     string `json:"text,omitempty"`
+	Audio    string `json:"audio,omitempty"`
+	Filename string `json:"filename,omitempty"`
+}
+
+// interpreterEvent is pushed to every participant in the room.
+type interpreterEvent struct {
+	Type         string                          `json:"type"` // "joined", "left", "utterance", "closed", "error"
+	UserID       string                          `json:"userId,omitempty"`
+	Participants []models.InterpreterParticipant `json:"participants,omitempty"`
+	Utterance    *models.Utterance               `json:"utterance,omitempty"`
+	Error        string                          `json:"error,omitempty"`
+}
+
+type interpreterClient struct {
+	userID primitive.ObjectID
+	lang   string
+	conn   *websocket.Conn
+	mu     sync.Mutex // websocket connections allow one writer at a time
+}
+
+func (cl *interpreterClient) send(ev interpreterEvent) {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if err := cl.conn.WriteJSON(ev); err != nil {
+		log.Printf("Interpreter: write to %s failed: %v", cl.userID.Hex(), err)
+	}
+}
+
+type interpreterRoom struct {
+	mu      sync.Mutex
+	clients map[primitive.ObjectID]*interpreterClient
+}
+
+func (r *interpreterRoom) broadcast(ev interpreterEvent) {
+	r.mu.Lock()
+	clients := make([]*interpreterClient, 0, len(r.clients))
+	for _, cl := range r.clients {
+		clients = append(clients, cl)
+	}
+	r.mu.Unlock()
+	for _, cl := range clients {
+		cl.send(ev)
+	}
+}
+
+// Live rooms are kept in memory per session ID; the transcript is in Mongo.
+var (
+	interpreterMu    sync.Mutex
+	interpreterRooms = map[primitive.ObjectID]*interpreterRoom{}
+)
+
+// interpreterRoomOf returns the live room of a session, or nil when nobody
+// is connected.
+func interpreterRoomOf(id primitive.ObjectID) *interpreterRoom {
+	interpreterMu.Lock()
+	defer interpreterMu.Unlock()
+	return interpreterRooms[id]
+}
+
+// broadcastInterpreter sends ev to everyone connected to a session.
+func broadcastInterpreter(id primitive.ObjectID, ev interpreterEvent) {
+	if room := interpreterRoomOf(id); room != nil {
+		room.broadcast(ev)
+	}
+}
+
+// joinInterpreterRoom adds cl to the session's room, creating it if needed.
+// The map lock is held throughout so a concurrent last leave can't delete
+// the room in between.
+func joinInterpreterRoom(id primitive.ObjectID, cl *interpreterClient) *interpreterRoom {
+	interpreterMu.Lock()
+	defer interpreterMu.Unlock()
+	room, ok := interpreterRooms[id]
+	if !ok {
+		room = &interpreterRoom{clients: map[primitive.ObjectID]*interpreterClient{}}
+		interpreterRooms[id] = room
+	}
+	room.mu.Lock()
+	if prev, ok := room.clients[cl.userID]; ok {
+		// A reconnect replaces the stale connection
+		prev.conn.Close()
+	}
+	room.clients[cl.userID] = cl
+	room.mu.Unlock()
+	return room
+}
+
+func leaveInterpreterRoom(id primitive.ObjectID, cl *interpreterClient) {
+	interpreterMu.Lock()
+	defer interpreterMu.Unlock()
+	room, ok := interpreterRooms[id]
+	if !ok {
+		return
+	}
+	room.mu.Lock()
+	if room.clients[cl.userID] == cl {
+		delete(room.clients, cl.userID)
+	}
+	empty := len(room.clients) == 0
+	room.mu.Unlock()
+	if empty {
+		delete(interpreterRooms, id)
+	}
+}
+
+// findInterpreterSession loads a session the user participates in.
+func findInterpreterSession(sessionID, userID primitive.ObjectID) (*models.InterpreterSession, error) {
+	var session models.InterpreterSession
+	err := database.GetCollection("interpreter_sessions").FindOne(
+		context.Background(),
+		bson.M{"_id": sessionID, "participants.userId": userID},
+	).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func userDisplayName(userID primitive.ObjectID) string {
+	var user models.User
+	if err := database.GetCollection("users").FindOne(context.Background(), bson.M{"_id": userID}).Decode(&user); err != nil {
+		return ""
+	}
+	return user.Name
+}
+
+// CreateInterpreterSession opens a session with the caller as first
+// participant. The returned joinToken is the invitation for the second
+// participant; only its hash is stored.
+func CreateInterpreterSession(c *fiber.Ctx) error {
+	var req models.InterpreterJoinRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	token, err := newToken()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create session")
+	}
+
+	session := models.InterpreterSession{
+		ID:            primitive.NewObjectID(),
+		CreatedBy:     userObjID,
+		Title:         req.Title,
+		JoinTokenHash: tokenHash(token),
+		Participants: []models.InterpreterParticipant{{
+			UserID:   userObjID,
+			Name:     userDisplayName(userObjID),
+			Language: baseLang(req.Language),
+			JoinedAt: time.Now(),
+		}},
+		Status:    "open",
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	if _, err := database.GetCollection("interpreter_sessions").InsertOne(context.Background(), session); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create session")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"session":   session,
+		"joinToken": token,
+	})
+}
+
+// JoinInterpreterSession adds the caller as the second participant, or
+// changes their language if they already joined. Joining needs the
+// session's joinToken; a wrong one is reported as a missing session.
+func JoinInterpreterSession(c *fiber.Ctx) error {
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	var req models.InterpreterJoinRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	lang := baseLang(req.Language)
+	collection := database.GetCollection("interpreter_sessions")
+
+	// Already a participant: only the language can change
+	res, err := collection.UpdateOne(
+		context.Background(),
+		bson.M{"_id": sessionID, "status": "open", "participants.userId": userObjID},
+		bson.M{"$set": bson.M{"participants.$.language": lang, "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to join session")
+	}
+
+	if res.MatchedCount == 0 {
+		if req.JoinToken == "" {
+			return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found")
+		}
+		// The size guard in the filter makes the seat check atomic
+		participant := models.InterpreterParticipant{
+			UserID:   userObjID,
+			Name:     userDisplayName(userObjID),
+			Language: lang,
+			JoinedAt: time.Now(),
+		}
+		hash := tokenHash(req.JoinToken)
+		res, err = collection.UpdateOne(
+			context.Background(),
+			bson.M{
+				"_id":                 sessionID,
+				"joinTokenHash":       hash,
+				"status":              "open",
+				"participants.userId": bson.M{"$ne": userObjID},
+				"$expr":               bson.M{"$lt": bson.A{bson.M{"$size": "$participants"}, maxInterpreterParticipants}},
+			},
+			bson.M{
+				"$push": bson.M{"participants": participant},
+				"$set":  bson.M{"updatedAt": time.Now()},
+			},
+		)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to join session")
+		}
+		if res.MatchedCount == 0 {
+			// Tell a wrong invitation apart from a closed or full session
+			invited := bson.M{"_id": sessionID, "joinTokenHash": hash}
+			if n, err := collection.CountDocuments(context.Background(), invited); err == nil && n == 0 {
+				return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found")
+			}
+			return utils.ErrorResponse(c, fiber.StatusConflict, "Session is closed or full")
+		}
+	}
+
+	session, err := findInterpreterSession(sessionID, userObjID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch session")
+	}
+	broadcastInterpreter(sessionID, interpreterEvent{
+		Type:         "joined",
+		UserID:       userID,
+		Participants: session.Participants,
+	})
+
+	return c.JSON(fiber.Map{
+		"session": session,
+	})
+}
+
+// GetInterpreterSession returns the session transcript to a participant
+func GetInterpreterSession(c *fiber.Ctx) error {
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	session, err := findInterpreterSession(sessionID, userObjID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: 1}, {Key: "_id", Value: 1}})
+	cursor, err := database.GetCollection("interpreter_utterances").Find(context.Background(), bson.M{"sessionId": sessionID}, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch transcript")
+	}
+	session.Utterances = []models.Utterance{}
+	if err := cursor.All(context.Background(), &session.Utterances); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode transcript")
+	}
+
+	return c.JSON(fiber.Map{
+		"session": session,
+	})
+}
+
+// CloseInterpreterSession ends a session and disconnects both sides
+func CloseInterpreterSession(c *fiber.Ctx) error {
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	res, err := database.GetCollection("interpreter_sessions").UpdateOne(
+		context.Background(),
+		bson.M{"_id": sessionID, "participants.userId": userObjID},
+		bson.M{"$set": bson.M{"status": "closed", "updatedAt": time.Now()}},
+	)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to close session")
+	}
+	if res.MatchedCount == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found")
+	}
+
+	if room := interpreterRoomOf(sessionID); room != nil {
+		room.broadcast(interpreterEvent{Type: "closed", UserID: userID})
+		room.mu.Lock()
+		for _, cl := range room.clients {
+			cl.conn.Close()
+		}
+		room.mu.Unlock()
+	}
+
+	return c.JSON(fiber.Map{
+		"status": "closed",
+	})
+}
+
+// InterpreterUpgrade checks the WebSocket upgrade and session membership
+// before handing over to InterpreterSocket.
+func InterpreterUpgrade(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+
+	sessionID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid session ID")
+	}
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	session, err := findInterpreterSession(sessionID, userObjID)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Session not found")
+	}
+	if session.Status != "open" {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Session is closed")
+	}
+
+	c.Locals("sessionId", sessionID)
+	c.Locals("userObjId", userObjID)
+	return c.Next()
+}
+
+// InterpreterSocket relays utterances between the two participants,
+// translating each one into the listener's language.
+func InterpreterSocket(conn *websocket.Conn) {
+	sessionID := conn.Locals("sessionId").(primitive.ObjectID)
+	userObjID := conn.Locals("userObjId").(primitive.ObjectID)
+
+	session, err := findInterpreterSession(sessionID, userObjID)
+	if err != nil {
+		conn.WriteJSON(interpreterEvent{Type: "error", Error: "Session not found"})
+		return
+	}
+
+	cl := &interpreterClient{userID: userObjID, conn: conn}
+	for _, p := range session.Participants {
+		if p.UserID == userObjID {
+			cl.lang = p.Language
+		}
+	}
+
+	conn.SetReadLimit(maxInterpreterMessage)
+
+	room := joinInterpreterRoom(sessionID, cl)
+	defer func() {
+		leaveInterpreterRoom(sessionID, cl)
+		room.broadcast(interpreterEvent{Type: "left", UserID: userObjID.Hex()})
+	}()
+
+	room.broadcast(interpreterEvent{Type: "joined", UserID: userObjID.Hex(), Participants: session.Participants})
+
+	for {
+		msgType, data, err := conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				log.Printf("Interpreter: %s sent a message over %d bytes", userObjID.Hex(), maxInterpreterMessage)
+			} else if websocket.IsUnexpectedCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				log.Printf("Interpreter: read from %s failed: %v", userObjID.Hex(), err)
+			}
+			return
+		}
+
+		var in interpreterInbound
+		if msgType == websocket.BinaryMessage {
+			in = interpreterInbound{Type: "audio", Audio: base64.StdEncoding.EncodeToString(data)}
+		} else if err := json.Unmarshal(data, &in); err != nil {
+			cl.send(interpreterEvent{Type: "error", Error: "Invalid message"})
+			continue
+		}
+
+		utterance, err := interpretUtterance(sessionID, cl, in)
+		if err != nil {
+			cl.send(interpreterEvent{Type: "error", Error: err.Error()})
+			continue
+		}
+		room.broadcast(interpreterEvent{Type: "utterance", UserID: userObjID.Hex(), Utterance: utterance})
+	}
+}
+
+// interpretUtterance transcribes (for audio) and translates one turn into
+// every other participant's language, then appends it to the transcript.
+func interpretUtterance(sessionID primitive.ObjectID, cl *interpreterClient, in interpreterInbound) (*models.Utterance, error) {
+	// Re-read participants so a late joiner or a language change is picked up
+	session, err := findInterpreterSession(sessionID, cl.userID)
+	if err != nil {
+		return nil, fiber.NewError(fiber.StatusNotFound, "Session not found")
+	}
+	if session.Status != "open" {
+		return nil, fiber.NewError(fiber.StatusConflict, "Session is closed")
+	}
+	lang := cl.lang
+	for _, p := range session.Participants {
+		if p.UserID == cl.userID {
+			lang = p.Language
+		}
+	}
+
+	text := strings.TrimSpace(in.Text)
+	mode := "text"
+
+	switch in.Type {
+	case "text", "":
+	case "audio":
+		if !sttAvailable() {
+			return nil, fiber.NewError(fiber.StatusServiceUnavailable, "Speech input is not available")
+		}
+		data, err := base64.StdEncoding.DecodeString(in.Audio)
+		if err != nil || len(data) == 0 {
+			return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid audio")
+		}
+		filename := in.Filename
+		if filename == "" {
+			filename = "utterance.webm"
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		transcript, err := sttProvider("", lang).Transcribe(ctx, data, filename, lang)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadGateway, "STT failed: "+err.Error())
+		}
+		text = transcript.Text
+		mode = "speech"
+	default:
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Unknown message type")
+	}
+	if text == "" {
+		return nil, fiber.NewError(fiber.StatusBadRequest, "Nothing to translate")
+	}
+	if verdict := moderateText(cl.userID, "/api/v1/interpreter/sessions/:id/ws", text); verdict.Flagged {
+		return nil, fiber.NewError(fiber.StatusUnprocessableEntity, refusedMessage)
+	}
+
+	ctx, usage := services.WithUsage(context.Background())
+	defer func() { recordTranslationUsage(cl.userID, usage.Total()) }()
+	translations := map[string]string{}
+	for _, p := range session.Participants {
+		if p.UserID == cl.userID || p.Language == lang {
+			continue
+		}
+		if _, done := translations[p.Language]; done {
+			continue
+		}
+		translated, err := services.TranslateText(ctx, text, lang, p.Language)
+		if err != nil {
+			return nil, fiber.NewError(fiber.StatusBadGateway, "Translation failed: "+err.Error())
+		}
+		translations[p.Language] = translated
+	}
+
+	utterance := models.Utterance{
+		ID:           primitive.NewObjectID(),
+		SessionID:    sessionID,
+		SpeakerID:    cl.userID,
+		Language:     lang,
+		Text:         text,
+		InputMode:    mode,
+		Translations: translations,
+		CreatedAt:    time.Now(),
+	}
+
+	if _, err := database.GetCollection("interpreter_utterances").InsertOne(context.Background(), utterance); err != nil {
+		log.Printf("Interpreter: failed to save utterance for session %s: %v", sessionID.Hex(), err)
+	}
+	_, err = database.GetCollection("interpreter_sessions").UpdateOne(
+		context.Background(),
+		bson.M{"_id": sessionID},
+		bson.M{"$set": bson.M{"updatedAt": time.Now()}},
+	)
+	if err != nil {
+		log.Printf("Interpreter: failed to touch session %s: %v", sessionID.Hex(), err)
+	}
+	return &utterance, nil
+}
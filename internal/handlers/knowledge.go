@@ -0,0 +1,507 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// maxKnowledgeBytes caps an uploaded document.
+const maxKnowledgeBytes = 2 * 1024 * 1024
+
+// embedBatchSize is how many chunks are embedded per API call.
+const embedBatchSize = 64
+
+var (
+	embedderOnce sync.Once
+	embedder     *services.Embedder
+)
+
+func getEmbedder() *services.Embedder {
+	embedderOnce.Do(func() { embedder = services.NewEmbedder() })
+	return embedder
+}
+
+// knowledgeIndex is every chunk embedded with the current model, held in
+// memory for cosine search. It is dropped whenever documents change.
+type knowledgeIndex struct {
+	chunks  []models.KnowledgeChunk
+	vectors [][]float32
+	docs    map[primitive.ObjectID]models.KnowledgeDocument
+}
+
+var (
+	knowledgeMu    sync.RWMutex
+	knowledgeCache *knowledgeIndex
+	// knowledgeGen counts invalidations, so a load that overlaps one does
+	// not cache what it read.
+	knowledgeGen uint64
+)
+
+func loadKnowledgeIndex(ctx context.Context) (*knowledgeIndex, error) {
+	knowledgeMu.RLock()
+	idx, gen := knowledgeCache, knowledgeGen
+	knowledgeMu.RUnlock()
+	if idx != nil {
+		return idx, nil
+	}
+
+	cursor, err := database.GetCollection("knowledge_documents").Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var docs []models.KnowledgeDocument
+	if err := cursor.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	cursor, err = database.GetCollection("knowledge_chunks").Find(ctx, bson.M{"model": getEmbedder().Model()})
+	if err != nil {
+		return nil, err
+	}
+	idx = &knowledgeIndex{docs: map[primitive.ObjectID]models.KnowledgeDocument{}}
+	if err := cursor.All(ctx, &idx.chunks); err != nil {
+		return nil, err
+	}
+	for _, d := range docs {
+		idx.docs[d.ID] = d
+	}
+	idx.vectors = make([][]float32, len(idx.chunks))
+	for i, ch := range idx.chunks {
+		idx.vectors[i] = ch.Embedding
+	}
+
+	knowledgeMu.Lock()
+	if knowledgeGen == gen {
+		knowledgeCache = idx
+	}
+	knowledgeMu.Unlock()
+	return idx, nil
+}
+
+func invalidateKnowledge() {
+	knowledgeMu.Lock()
+	knowledgeCache = nil
+	knowledgeGen++
+	knowledgeMu.Unlock()
+}
+
+// knowledgeTopK reads KNOWLEDGE_TOP_K, the number of passages per answer.
+func knowledgeTopK() int {
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("KNOWLEDGE_TOP_K"))); err == nil {
+		return n
+	}
+	return 4
+}
+
+// searchKnowledge finds the passages most similar to query. With
+// KNOWLEDGE_VECTOR_INDEX naming an Atlas Vector Search index on
+// knowledge_chunks.embedding (with "model" as a filter field) the search
+// runs in the database; otherwise, or if that fails, it runs in memory.
+func searchKnowledge(ctx context.Context, query string, k int) ([]services.Passage, []models.Citation, error) {
+	if k <= 0 || strings.TrimSpace(query) == "" {
+		return nil, nil, nil
+	}
+	idx, err := loadKnowledgeIndex(ctx)
+	if err != nil || len(idx.chunks) == 0 {
+		return nil, nil, err
+	}
+
+	emb := getEmbedder()
+	vectors, err := emb.Embed(ctx, []string{query})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var hits []knowledgeHit
+	if index := strings.TrimSpace(os.Getenv("KNOWLEDGE_VECTOR_INDEX")); index != "" {
+		hits, err = atlasVectorSearch(ctx, index, vectors[0], k, emb.MinScore())
+		if err != nil {
+			log.Printf("Knowledge: vector search failed, searching in memory: %v", err)
+		}
+	}
+	if hits == nil {
+		for _, s := range services.NearestVectors(vectors[0], idx.vectors, k, emb.MinScore()) {
+			hits = append(hits, knowledgeHit{Chunk: idx.chunks[s.Index], Score: s.Score})
+		}
+	}
+
+	passages := make([]services.Passage, 0, len(hits))
+	citations := make([]models.Citation, 0, len(hits))
+	for i, h := range hits {
+		doc := idx.docs[h.Chunk.DocumentID]
+		passages = append(passages, services.Passage{
+			Number:  i + 1,
+			Title:   doc.Title,
+			Heading: h.Chunk.Heading,
+			Text:    h.Chunk.Text,
+		})
+		citations = append(citations, models.Citation{
+			Number:     i + 1,
+			DocumentID: h.Chunk.DocumentID,
+			ChunkID:    h.Chunk.ID,
+			Title:      doc.Title,
+			Source:     doc.Source,
+			Heading:    h.Chunk.Heading,
+			Score:      h.Score,
+		})
+	}
+	return passages, citations, nil
+}
+
+type knowledgeHit struct {
+	Chunk models.KnowledgeChunk `bson:",inline"`
+	Score float64               `bson:"score"`
+}
+
+func atlasVectorSearch(ctx context.Context, index string, query []float32, k int, minScore float64) ([]knowledgeHit, error) {
+	pipeline := []bson.M{
+		{"$vectorSearch": bson.M{
+			"index":         index,
+			"path":          "embedding",
+			"queryVector":   query,
+			"numCandidates": k * 20,
+			"limit":         k,
+			"filter":        bson.M{"model": getEmbedder().Model()},
+		}},
+		{"$project": bson.M{"embedding": 0, "score": bson.M{"$meta": "vectorSearchScore"}}},
+	}
+	cursor, err := database.GetCollection("knowledge_chunks").Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var found []knowledgeHit
+	if err := cursor.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	hits := []knowledgeHit{}
+	for _, h := range found {
+		// Atlas reports cosine similarity rescaled to 0..1
+		h.Score = 2*h.Score - 1
+		if h.Score >= minScore {
+			hits = append(hits, h)
+		}
+	}
+	return hits, nil
+}
+
+var citationMarker = regexp.MustCompile(`\[(\d+)\]`)
+
+// citedSources keeps the citations whose [n] marker appears in answer.
+func citedSources(answer string, citations []models.Citation) []models.Citation {
+	used := map[int]bool{}
+	for _, m := range citationMarker.FindAllStringSubmatch(answer, -1) {
+		n, _ := strconv.Atoi(m[1])
+		used[n] = true
+	}
+	var out []models.Citation
+	for _, c := range citations {
+		if used[c.Number] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// embedChunks embeds texts in batches.
+func embedChunks(ctx context.Context, texts []string) ([][]float32, error) {
+	var out [][]float32
+	for start := 0; start < len(texts); start += embedBatchSize {
+		end := min(start+embedBatchSize, len(texts))
+		vectors, err := getEmbedder().Embed(ctx, texts[start:end])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, vectors...)
+	}
+	return out, nil
+}
+
+// GetKnowledgeDocuments lists the ingested documents
+func GetKnowledgeDocuments(c *fiber.Ctx) error {
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := database.GetCollection("knowledge_documents").Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch documents")
+	}
+	defer cursor.Close(context.Background())
+
+	docs := []models.KnowledgeDocument{}
+	if err := cursor.All(context.Background(), &docs); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode documents")
+	}
+
+	return c.JSON(fiber.Map{
+		"documents":      docs,
+		"embeddingModel": getEmbedder().Model(),
+	})
+}
+
+// GetKnowledgeDocument returns a document with its chunks
+func GetKnowledgeDocument(c *fiber.Ctx) error {
+	docID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid document ID")
+	}
+
+	var doc models.KnowledgeDocument
+	err = database.GetCollection("knowledge_documents").FindOne(context.Background(), bson.M{"_id": docID}).Decode(&doc)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Document not found")
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "index", Value: 1}}).SetProjection(bson.M{"embedding": 0})
+	cursor, err := database.GetCollection("knowledge_chunks").Find(context.Background(), bson.M{"documentId": docID}, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch chunks")
+	}
+	defer cursor.Close(context.Background())
+
+	chunks := []models.KnowledgeChunk{}
+	if err := cursor.All(context.Background(), &chunks); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode chunks")
+	}
+
+	return c.JSON(fiber.Map{
+		"document": doc,
+		"chunks":   chunks,
+	})
+}
+
+// CreateKnowledgeDocument ingests a Markdown or plain-text document, sent
+// as JSON content or as a multipart "file" upload. PDFs must be converted
+// to text first.
+func CreateKnowledgeDocument(c *fiber.Ctx) error {
+	var req models.KnowledgeDocumentRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+
+	if file, err := c.FormFile("file"); err == nil {
+		ext := strings.ToLower(filepath.Ext(file.Filename))
+		switch ext {
+		case ".md", ".markdown":
+			req.Format = models.KnowledgeFormatMarkdown
+		case ".txt":
+			req.Format = models.KnowledgeFormatText
+		case ".pdf":
+			return utils.ErrorResponse(c, fiber.StatusUnsupportedMediaType, "Upload the PDF's extracted text as a .txt file")
+		default:
+			return utils.ErrorResponse(c, fiber.StatusUnsupportedMediaType, "Unsupported file type; use .md or .txt")
+		}
+		if file.Size > maxKnowledgeBytes {
+			return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "Document is too large")
+		}
+		f, err := file.Open()
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to read file")
+		}
+		data, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to read file")
+		}
+		req.Content = string(data)
+		if req.Title == "" {
+			req.Title = strings.TrimSuffix(file.Filename, filepath.Ext(file.Filename))
+		}
+	}
+
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if len(req.Content) > maxKnowledgeBytes {
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "Document is too large")
+	}
+	if req.Format == "" {
+		req.Format = models.KnowledgeFormatMarkdown
+	}
+	if req.Language == "" {
+		req.Language = "en"
+	}
+
+	pieces := services.ChunkDocument(req.Content, req.Format)
+	if len(pieces) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Document has no text")
+	}
+
+	texts := make([]string, len(pieces))
+	for i, p := range pieces {
+		// The heading helps match passages whose text never names the topic
+		texts[i] = strings.TrimSpace(p.Heading + "\n" + p.Text)
+	}
+	vectors, err := embedChunks(context.Background(), texts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Failed to embed document: "+err.Error())
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	doc := models.KnowledgeDocument{
+		ID:             primitive.NewObjectID(),
+		Title:          strings.TrimSpace(req.Title),
+		Source:         strings.TrimSpace(req.Source),
+		Language:       baseLang(req.Language),
+		Format:         req.Format,
+		ChunkCount:     len(pieces),
+		EmbeddingModel: getEmbedder().Model(),
+		CreatedBy:      userObjID,
+		CreatedAt:      time.Now(),
+	}
+	chunks := make([]any, len(pieces))
+	for i, p := range pieces {
+		chunks[i] = models.KnowledgeChunk{
+			ID:         primitive.NewObjectID(),
+			DocumentID: doc.ID,
+			Index:      i,
+			Heading:    p.Heading,
+			Text:       p.Text,
+			Embedding:  vectors[i],
+			Model:      doc.EmbeddingModel,
+			CreatedAt:  doc.CreatedAt,
+		}
+	}
+
+	if _, err := database.GetCollection("knowledge_chunks").InsertMany(context.Background(), chunks); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save document")
+	}
+	if _, err := database.GetCollection("knowledge_documents").InsertOne(context.Background(), doc); err != nil {
+		database.GetCollection("knowledge_chunks").DeleteMany(context.Background(), bson.M{"documentId": doc.ID})
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save document")
+	}
+	invalidateKnowledge()
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"document": doc,
+	})
+}
+
+// DeleteKnowledgeDocument removes a document and its chunks
+func DeleteKnowledgeDocument(c *fiber.Ctx) error {
+	docID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid document ID")
+	}
+
+	err = database.GetCollection("knowledge_documents").FindOneAndDelete(context.Background(), bson.M{"_id": docID}).Err()
+	if err == mongo.ErrNoDocuments {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Document not found")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete document")
+	}
+	if _, err := database.GetCollection("knowledge_chunks").DeleteMany(context.Background(), bson.M{"documentId": docID}); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete document chunks")
+	}
+	invalidateKnowledge()
+
+	return c.JSON(fiber.Map{
+		"message": "Document deleted",
+	})
+}
+
+// ReindexKnowledge re-embeds chunks stored with a different embedding
+// model, e.g. after EMBEDDING_MODEL changed.
+func ReindexKnowledge(c *fiber.Ctx) error {
+	model := getEmbedder().Model()
+	collection := database.GetCollection("knowledge_chunks")
+
+	cursor, err := collection.Find(context.Background(), bson.M{"model": bson.M{"$ne": model}}, options.Find().SetProjection(bson.M{"embedding": 0}))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch chunks")
+	}
+	var stale []models.KnowledgeChunk
+	if err := cursor.All(context.Background(), &stale); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode chunks")
+	}
+
+	texts := make([]string, len(stale))
+	for i, ch := range stale {
+		texts[i] = strings.TrimSpace(ch.Heading + "\n" + ch.Text)
+	}
+	vectors, err := embedChunks(context.Background(), texts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "Failed to embed chunks: "+err.Error())
+	}
+
+	var writes []mongo.WriteModel
+	for i, ch := range stale {
+		writes = append(writes, mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": ch.ID}).
+			SetUpdate(bson.M{"$set": bson.M{"embedding": vectors[i], "model": model}}))
+	}
+	if len(writes) > 0 {
+		if _, err := collection.BulkWrite(context.Background(), writes); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save embeddings")
+		}
+		_, err := database.GetCollection("knowledge_documents").UpdateMany(context.Background(), bson.M{}, bson.M{"$set": bson.M{"embeddingModel": model}})
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update documents")
+		}
+	}
+	invalidateKnowledge()
+
+	return c.JSON(fiber.Map{
+		"reindexed":      len(stale),
+		"embeddingModel": model,
+	})
+}
+
+// maxKnowledgeSearch caps SearchKnowledge's limit; Atlas rejects vector
+// searches with too many candidates (limit × 20).
+const maxKnowledgeSearch = 20
+
+// SearchKnowledge shows which passages a question would retrieve, at most
+// maxKnowledgeSearch
+func SearchKnowledge(c *fiber.Ctx) error {
+	var req struct {
+		Query string `json:"query" validate:"required"`
+		Limit int    `json:"limit"`
+	}
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	if req.Limit <= 0 {
+		req.Limit = knowledgeTopK()
+	}
+	req.Limit = min(req.Limit, maxKnowledgeSearch)
+
+	passages, citations, err := searchKnowledge(context.Background(), req.Query, req.Limit)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Search failed: "+err.Error())
+	}
+
+	results := make([]fiber.Map, len(passages))
+	for i, p := range passages {
+		results[i] = fiber.Map{
+			"citation": citations[i],
+			"text":     p.Text,
+		}
+	}
+	return c.JSON(fiber.Map{
+		"results": results,
+	})
+}
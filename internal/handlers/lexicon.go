@@ -0,0 +1,253 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// Compiled lexicons are cached per base language and dropped whenever an
+// admin edits that language's entries.
+var (
+	lexiconMu    sync.RWMutex
+	lexiconCache = map[string]*services.Lexicon{}
+)
+
+// lexiconFor returns the compiled pronunciation lexicon for lang.
+func lexiconFor(lang string) (*services.Lexicon, error) {
+	key := baseLang(lang)
+
+	lexiconMu.RLock()
+	lex, ok := lexiconCache[key]
+	lexiconMu.RUnlock()
+	if ok {
+		return lex, nil
+	}
+
+	collection := database.GetCollection("lexicon")
+	cursor, err := collection.Find(context.Background(), bson.M{"language": key})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.Background())
+
+	var entries []models.LexiconEntry
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return nil, err
+	}
+
+	lex, err = services.NewLexicon(entries)
+	if err != nil {
+		return nil, err
+	}
+
+	lexiconMu.Lock()
+	lexiconCache[key] = lex
+	lexiconMu.Unlock()
+	return lex, nil
+}
+
+func invalidateLexicon(lang string) {
+	lexiconMu.Lock()
+	delete(lexiconCache, baseLang(lang))
+	lexiconMu.Unlock()
+}
+
+// GetLexicon lists lexicon entries, optionally filtered by ?lang=
+func GetLexicon(c *fiber.Ctx) error {
+	filter := bson.M{}
+	if lang := c.Query("lang"); lang != "" {
+		filter["language"] = baseLang(lang)
+	}
+
+	collection := database.GetCollection("lexicon")
+	opts := options.Find().SetSort(bson.D{{Key: "language", Value: 1}, {Key: "grapheme", Value: 1}})
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch lexicon")
+	}
+	defer cursor.Close(context.Background())
+
+	entries := []models.LexiconEntry{}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode lexicon")
+	}
+
+	return c.JSON(fiber.Map{
+		"entries": entries,
+	})
+}
+
+// CreateLexiconEntry adds a pronunciation for a word in one language
+func CreateLexiconEntry(c *fiber.Ctx) error {
+	var req models.LexiconEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	grapheme := strings.TrimSpace(req.Grapheme)
+	if grapheme == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "grapheme must not be blank")
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	entry := models.LexiconEntry{
+		ID:            primitive.NewObjectID(),
+		Language:      baseLang(req.Language),
+		Grapheme:      grapheme,
+		Respelling:    strings.TrimSpace(req.Respelling),
+		IPA:           strings.TrimSpace(req.IPA),
+		CaseSensitive: req.CaseSensitive,
+		Note:          req.Note,
+		CreatedBy:     userObjID,
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}
+
+	collection := database.GetCollection("lexicon")
+	err := collection.FindOne(context.Background(), bson.M{"language": entry.Language, "grapheme": entry.Grapheme}).Err()
+	if err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Entry already exists for this language")
+	}
+
+	if _, err := collection.InsertOne(context.Background(), entry); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save lexicon entry")
+	}
+	invalidateLexicon(entry.Language)
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"entry": entry,
+	})
+}
+
+// UpdateLexiconEntry replaces the editable fields of an entry
+func UpdateLexiconEntry(c *fiber.Ctx) error {
+	entryID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid entry ID")
+	}
+
+	var req models.LexiconEntryRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	grapheme := strings.TrimSpace(req.Grapheme)
+	if grapheme == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "grapheme must not be blank")
+	}
+
+	lang := baseLang(req.Language)
+	collection := database.GetCollection("lexicon")
+	err = collection.FindOne(context.Background(), bson.M{"_id": bson.M{"$ne": entryID}, "language": lang, "grapheme": grapheme}).Err()
+	if err == nil {
+		return utils.ErrorResponse(c, fiber.StatusConflict, "Entry already exists for this language")
+	}
+
+	var previous models.LexiconEntry
+	err = collection.FindOneAndUpdate(
+		context.Background(),
+		bson.M{"_id": entryID},
+		bson.M{"$set": bson.M{
+			"language":      lang,
+			"grapheme":      grapheme,
+			"respelling":    strings.TrimSpace(req.Respelling),
+			"ipa":           strings.TrimSpace(req.IPA),
+			"caseSensitive": req.CaseSensitive,
+			"note":          req.Note,
+			"updatedAt":     time.Now(),
+		}},
+	).Decode(&previous)
+	if err == mongo.ErrNoDocuments {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Lexicon entry not found")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to update lexicon entry")
+	}
+	invalidateLexicon(previous.Language)
+	invalidateLexicon(req.Language)
+
+	var entry models.LexiconEntry
+	if err := collection.FindOne(context.Background(), bson.M{"_id": entryID}).Decode(&entry); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch lexicon entry")
+	}
+
+	return c.JSON(fiber.Map{
+		"entry": entry,
+	})
+}
+
+// DeleteLexiconEntry removes an entry
+func DeleteLexiconEntry(c *fiber.Ctx) error {
+	entryID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid entry ID")
+	}
+
+	collection := database.GetCollection("lexicon")
+	var entry models.LexiconEntry
+	err = collection.FindOneAndDelete(context.Background(), bson.M{"_id": entryID}).Decode(&entry)
+	if err == mongo.ErrNoDocuments {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Lexicon entry not found")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to delete lexicon entry")
+	}
+	invalidateLexicon(entry.Language)
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// PreviewLexicon shows how text will be rewritten for TTS and returns the
+// resulting audio (base64) so admins can check an entry by ear.
+func PreviewLexicon(c *fiber.Ctx) error {
+	var req models.LexiconPreviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	lang := normalizeLang(req.Lang)
+	doc, err := services.ParseSpeech(req.Text)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	audioBytes, ctype, rewritten, err := speak(doc, lang)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "TTS failed: "+err.Error())
+	}
+
+	resp := fiber.Map{
+		"original":    doc.PlainText(),
+		"rewritten":   rewritten.PlainText(),
+		"provider":    ttsProvider(lang).Name,
+		"contentType": ctype,
+		"audio":       base64.StdEncoding.EncodeToString(audioBytes),
+	}
+	if rewritten.IsSSML {
+		resp["ssml"] = rewritten.SSML()
+	}
+	return c.JSON(resp)
+}
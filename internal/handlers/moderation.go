@@ -0,0 +1,173 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+var (
+	moderatorOnce sync.Once
+	moderator     *services.Moderator
+)
+
+// moderateInput checks text against the blocked phrases and, when
+// MODERATION_CLASSIFIER=llm, asks the model as well. Refusals are recorded
+// without the text.
+func moderateInput(c *fiber.Ctx, text string) models.ModerationResult {
+	userObjID, _ := primitive.ObjectIDFromHex(c.Locals("userId").(string))
+	return moderateText(userObjID, c.Route().Path, text)
+}
+
+// moderateText is moderateInput for input that does not come with a
+// request of its own, such as interpreter socket messages.
+func moderateText(userObjID primitive.ObjectID, endpoint, text string) models.ModerationResult {
+	moderatorOnce.Do(func() {
+		rules, err := services.LoadModerationRules()
+		if err != nil {
+			log.Printf("Moderation: %v; using built-in rules", err)
+			rules = services.DefaultModerationRules
+		}
+		moderator = services.NewModerator(rules)
+	})
+
+	verdict := moderator.Check(text)
+	if llm := services.LLM(); !verdict.Flagged && llm != nil && strings.EqualFold(strings.TrimSpace(os.Getenv("MODERATION_CLASSIFIER")), "llm") {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		v, err := llm.Moderate(ctx, text)
+		if err != nil {
+			// An unavailable classifier must not block medical questions
+			log.Printf("Moderation: classifier failed: %v", err)
+		} else {
+			verdict = v
+		}
+	}
+	if !verdict.Flagged {
+		return verdict
+	}
+
+	event := models.ModerationEvent{
+		ID:               primitive.NewObjectID(),
+		UserID:           userObjID,
+		Endpoint:         endpoint,
+		ModerationResult: verdict,
+		CreatedAt:        time.Now(),
+	}
+	if _, err := database.GetCollection("moderation_events").InsertOne(context.Background(), event); err != nil {
+		log.Printf("Moderation: failed to record refusal: %v", err)
+	}
+	return verdict
+}
+
+// refusedMessage is the error given for flagged input.
+const refusedMessage = "Message refused: it appears to contain abusive content"
+
+// refuseInput answers a request whose text was flagged by moderateInput.
+func refuseInput(c *fiber.Ctx, verdict models.ModerationResult) error {
+	return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{
+		"error":    refusedMessage,
+		"category": verdict.Category,
+	})
+}
+
+// RecordRedaction stores a redaction made before an outbound call. It is
+// registered with services.SetRedactionLogger at startup.
+func RecordRedaction(entry models.RedactionLog) {
+	entry.ID = primitive.NewObjectID()
+	entry.CreatedAt = time.Now()
+	if _, err := database.GetCollection("redaction_log").InsertOne(context.Background(), entry); err != nil {
+		log.Printf("PII: failed to record redaction for %s: %v", entry.Service, err)
+	}
+}
+
+// adminPage reads ?page= and ?limit= (default 20, at most 100).
+func adminPage(c *fiber.Ctx) (page, limit int) {
+	page = c.QueryInt("page", 1)
+	if page < 1 {
+		page = 1
+	}
+	limit = c.QueryInt("limit", 20)
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	return page, limit
+}
+
+// GetRedactionLog lists redactions, newest first, optionally for one
+// ?service= (e.g. "llm", "translate", "tts:ElevenLabs").
+func GetRedactionLog(c *fiber.Ctx) error {
+	page, limit := adminPage(c)
+	filter := bson.M{}
+	if service := c.Query("service"); service != "" {
+		filter["service"] = service
+	}
+
+	collection := database.GetCollection("redaction_log")
+	total, err := collection.CountDocuments(context.Background(), filter)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch redaction log")
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(context.Background(), filter, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch redaction log")
+	}
+	entries := []models.RedactionLog{}
+	if err := cursor.All(context.Background(), &entries); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode redaction log")
+	}
+
+	return c.JSON(fiber.Map{
+		"entries": entries,
+		"page":    page,
+		"limit":   limit,
+		"total":   total,
+	})
+}
+
+// GetModerationEvents lists refused inputs, newest first.
+func GetModerationEvents(c *fiber.Ctx) error {
+	page, limit := adminPage(c)
+
+	collection := database.GetCollection("moderation_events")
+	total, err := collection.CountDocuments(context.Background(), bson.M{})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch moderation events")
+	}
+	opts := options.Find().
+		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSkip(int64((page - 1) * limit)).
+		SetLimit(int64(limit))
+	cursor, err := collection.Find(context.Background(), bson.M{}, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch moderation events")
+	}
+	events := []models.ModerationEvent{}
+	if err := cursor.All(context.Background(), &events); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode moderation events")
+	}
+
+	return c.JSON(fiber.Map{
+		"events": events,
+		"page":   page,
+		"limit":  limit,
+		"total":  total,
+	})
+}
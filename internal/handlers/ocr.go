@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// maxOCRUpload caps photo uploads; phone cameras rarely exceed this.
+const maxOCRUpload = 10 << 20
+
+// imageBlock is one OCR block with its translation, positioned so the
+// client can overlay it on the photo.
+type imageBlock struct {
+	Text           string               `json:"text"`
+	TranslatedText string               `json:"translatedText"`
+	Box            services.BoundingBox `json:"box"`
+	Confidence     float64              `json:"confidence"`
+}
+
+// ocrProvider picks the OCR backend from OCR_PROVIDER (tesseract|remote).
+// Without it, the remote service is used when OCR_REMOTE_URL is set.
+func ocrProvider() services.OCRProvider {
+	switch strings.ToLower(strings.TrimSpace(os.Getenv("OCR_PROVIDER"))) {
+	case "tesseract":
+		return services.TesseractOCR
+	case "remote":
+		return services.RemoteOCR
+	}
+	if strings.TrimSpace(os.Getenv("OCR_REMOTE_URL")) != "" {
+		return services.RemoteOCR
+	}
+	return services.TesseractOCR
+}
+
+// TranslateImage runs OCR on an uploaded photo ("image"), translates each
+// text block from "sourceLang" (default en) to "targetLang" and returns the
+// blocks with their bounding boxes.
+func TranslateImage(c *fiber.Ctx) error {
+	targetLang := strings.TrimSpace(c.FormValue("targetLang"))
+	if targetLang == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "targetLang is required")
+	}
+	sourceLang := strings.TrimSpace(c.FormValue("sourceLang"))
+	if sourceLang == "" {
+		sourceLang = "en"
+	}
+
+	fh, err := c.FormFile("image")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "image file is required")
+	}
+	if fh.Size > maxOCRUpload {
+		return utils.ErrorResponse(c, fiber.StatusRequestEntityTooLarge, "image must be under 10MB")
+	}
+	f, err := fh.Open()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to read image")
+	}
+	defer f.Close()
+	image, err := io.ReadAll(io.LimitReader(f, maxOCRUpload))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Failed to read image")
+	}
+
+	switch http.DetectContentType(image) {
+	case "image/png", "image/jpeg", "image/webp", "image/bmp":
+	default:
+		return utils.ErrorResponse(c, fiber.StatusUnsupportedMediaType, "image must be PNG, JPEG, WebP or BMP")
+	}
+
+	provider := ocrProvider()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	found, err := provider.Recognize(ctx, image, sourceLang)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadGateway, "OCR failed: "+err.Error())
+	}
+
+	texts := make([]string, 0, len(found))
+	for _, b := range found {
+		texts = append(texts, b.Text)
+	}
+	if verdict := moderateInput(c, strings.Join(texts, "\n")); verdict.Flagged {
+		return refuseInput(c, verdict)
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	translateCtx, usage := services.WithUsage(context.Background())
+	defer func() { recordTranslationUsage(userObjID, usage.Total()) }()
+	blocks := make([]imageBlock, 0, len(found))
+	sourceTexts := make([]string, 0, len(found))
+	translatedTexts := make([]string, 0, len(found))
+	for _, b := range found {
+		text := strings.TrimSpace(b.Text)
+		if text == "" {
+			continue
+		}
+		translated := text
+		if baseLang(sourceLang) != baseLang(targetLang) {
+			translated, err = services.TranslateText(translateCtx, text, sourceLang, targetLang)
+			if err != nil {
+				return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Translation failed: "+err.Error())
+			}
+		}
+		blocks = append(blocks, imageBlock{
+			Text:           text,
+			TranslatedText: translated,
+			Box:            b.Box,
+			Confidence:     b.Confidence,
+		})
+		sourceTexts = append(sourceTexts, text)
+		translatedTexts = append(translatedTexts, translated)
+	}
+	if len(blocks) == 0 {
+		return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "No text found in image")
+	}
+
+	// Keep it in the user's translation history like any other translation
+	translation := models.Translation{
+		ID:             primitive.NewObjectID(),
+		UserID:         userObjID,
+		SourceText:     strings.Join(sourceTexts, "\n\n"),
+		TranslatedText: strings.Join(translatedTexts, "\n\n"),
+		SourceLang:     sourceLang,
+		TargetLang:     targetLang,
+		InputMode:      "image",
+		CreatedAt:      time.Now(),
+	}
+	if _, err := database.GetCollection("translations").InsertOne(context.Background(), translation); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save translation")
+	}
+
+	return c.JSON(fiber.Map{
+		"translation": translation,
+		"blocks":      blocks,
+		"provider":    provider.Name,
+	})
+}
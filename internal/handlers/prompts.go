@@ -0,0 +1,322 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+var personaPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Active prompt templates are cached per persona and dropped whenever an
+// admin saves or activates a version.
+var (
+	promptMu    sync.RWMutex
+	promptCache = map[string]*models.PromptTemplate{}
+)
+
+// normalizePersona lowercases a persona name, defaulting to the medical
+// assistant.
+func normalizePersona(persona string) string {
+	persona = strings.ToLower(strings.TrimSpace(persona))
+	if persona == "" {
+		return services.DefaultPersona
+	}
+	return persona
+}
+
+// activePrompt returns the active template for persona. Personas without a
+// saved template fall back to their built-in prompt as version 0; nil means
+// the persona does not exist.
+func activePrompt(persona string) (*models.PromptTemplate, error) {
+	promptMu.RLock()
+	tmpl, ok := promptCache[persona]
+	promptMu.RUnlock()
+	if ok {
+		return tmpl, nil
+	}
+
+	var saved models.PromptTemplate
+	err := database.GetCollection("prompt_templates").FindOne(context.Background(), bson.M{"persona": persona, "active": true}).Decode(&saved)
+	switch {
+	case err == nil:
+		tmpl = &saved
+	case err == mongo.ErrNoDocuments:
+		builtin, ok := services.BuiltinPersonas[persona]
+		if !ok {
+			return nil, nil
+		}
+		tmpl = &models.PromptTemplate{Persona: persona, Name: builtin.Name, Template: builtin.Template, Active: true}
+	default:
+		return nil, err
+	}
+
+	promptMu.Lock()
+	promptCache[persona] = tmpl
+	promptMu.Unlock()
+	return tmpl, nil
+}
+
+func invalidatePrompt(persona string) {
+	promptMu.Lock()
+	delete(promptCache, persona)
+	promptMu.Unlock()
+}
+
+// requestLocale is the explicit locale, else the first Accept-Language tag.
+func requestLocale(c *fiber.Ctx, explicit string) string {
+	if explicit = strings.TrimSpace(explicit); explicit != "" {
+		return explicit
+	}
+	tag := strings.SplitN(c.Get("Accept-Language"), ",", 2)[0]
+	return strings.TrimSpace(strings.SplitN(tag, ";", 2)[0])
+}
+
+// systemPrompt renders the conversation persona's active prompt and says
+// which version it came from.
+func systemPrompt(conversation *models.Conversation, opts answerOptions) (string, models.PromptRef) {
+	persona := normalizePersona(conversation.Persona)
+	tmpl, err := activePrompt(persona)
+	if err != nil || tmpl == nil {
+		if err != nil {
+			log.Printf("Chat: failed to load prompt for persona %s: %v", persona, err)
+		}
+		persona = services.DefaultPersona
+		tmpl = &models.PromptTemplate{Persona: persona, Template: services.BuiltinPersonas[persona].Template}
+	}
+
+	vars := services.PromptVars{Language: opts.Lang, Locale: opts.Locale, UserName: opts.UserName}
+
+	ref := models.PromptRef{Persona: persona, Version: tmpl.Version}
+	if !tmpl.ID.IsZero() {
+		id := tmpl.ID
+		ref.TemplateID = &id
+	}
+
+	system, err := services.RenderPrompt(tmpl.Template, vars)
+	if err != nil {
+		log.Printf("Chat: %v (persona %s v%d); using built-in prompt", err, persona, tmpl.Version)
+		system, _ = services.RenderPrompt(services.BuiltinPersonas[services.DefaultPersona].Template, vars)
+		ref = models.PromptRef{Persona: services.DefaultPersona}
+	}
+	return system, ref
+}
+
+// GetPrompts lists the active prompt of every persona, or every version of
+// one persona with ?persona=
+func GetPrompts(c *fiber.Ctx) error {
+	collection := database.GetCollection("prompt_templates")
+
+	if persona := c.Query("persona"); persona != "" {
+		opts := options.Find().SetSort(bson.D{{Key: "version", Value: -1}})
+		cursor, err := collection.Find(context.Background(), bson.M{"persona": normalizePersona(persona)}, opts)
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch prompts")
+		}
+		defer cursor.Close(context.Background())
+
+		versions := []models.PromptTemplate{}
+		if err := cursor.All(context.Background(), &versions); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode prompts")
+		}
+		return c.JSON(fiber.Map{
+			"prompts": versions,
+		})
+	}
+
+	cursor, err := collection.Find(context.Background(), bson.M{"active": true})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch prompts")
+	}
+	defer cursor.Close(context.Background())
+
+	active := []models.PromptTemplate{}
+	if err := cursor.All(context.Background(), &active); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode prompts")
+	}
+	saved := map[string]bool{}
+	for _, p := range active {
+		saved[p.Persona] = true
+	}
+	for persona, builtin := range services.BuiltinPersonas {
+		if !saved[persona] {
+			active = append(active, models.PromptTemplate{Persona: persona, Name: builtin.Name, Template: builtin.Template, Active: true})
+		}
+	}
+	sort.Slice(active, func(i, j int) bool { return active[i].Persona < active[j].Persona })
+
+	return c.JSON(fiber.Map{
+		"prompts": active,
+	})
+}
+
+// promptVersionAttempts bounds retries when a concurrent save takes the
+// version number first.
+const promptVersionAttempts = 3
+
+// CreatePromptVersion saves a new version of a persona's prompt, active by
+// default. A persona that does not exist yet is created. Version numbers
+// are unique per persona; a save that loses the number to a concurrent one
+// takes the next.
+func CreatePromptVersion(c *fiber.Ctx) error {
+	var req models.PromptTemplateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	persona := normalizePersona(req.Persona)
+	if !personaPattern.MatchString(persona) {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Persona must be lowercase letters, digits and underscores")
+	}
+	sample := services.PromptVars{Language: "en", UserName: "Ada", Locale: "en-NG"}
+	if _, err := services.RenderPrompt(req.Template, sample); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	activate := req.Activate == nil || *req.Activate
+	tmpl := models.PromptTemplate{
+		ID:          primitive.NewObjectID(),
+		Persona:     persona,
+		Name:        strings.TrimSpace(req.Name),
+		Description: req.Description,
+		Template:    req.Template,
+		CreatedBy:   userObjID,
+		CreatedAt:   time.Now(),
+	}
+
+	collection := database.GetCollection("prompt_templates")
+	var err error
+	for attempt := 0; attempt < promptVersionAttempts; attempt++ {
+		var latest models.PromptTemplate
+		err = collection.FindOne(context.Background(), bson.M{"persona": persona},
+			options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&latest)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch prompts")
+		}
+		tmpl.Version = latest.Version + 1
+		// Saved inactive; activatePrompt switches it on with the others off
+		if _, err = collection.InsertOne(context.Background(), tmpl); !mongo.IsDuplicateKeyError(err) {
+			break
+		}
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save prompt")
+	}
+	if activate {
+		if err := activatePrompt(persona, tmpl.ID); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to activate prompt")
+		}
+		tmpl.Active = true
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"prompt": tmpl,
+	})
+}
+
+// activatePrompt makes promptID the persona's only active version. Both
+// writes run in one transaction (MongoDB must be a replica set, as Atlas
+// is), so readers never see no active version, and concurrent activations
+// write the same documents and conflict rather than leave two.
+func activatePrompt(persona string, promptID primitive.ObjectID) error {
+	collection := database.GetCollection("prompt_templates")
+	session, err := database.Client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.Background())
+
+	_, err = session.WithTransaction(context.Background(), func(ctx mongo.SessionContext) (any, error) {
+		if _, err := collection.UpdateMany(ctx,
+			bson.M{"persona": persona, "_id": bson.M{"$ne": promptID}},
+			bson.M{"$set": bson.M{"active": false}},
+		); err != nil {
+			return nil, err
+		}
+		_, err := collection.UpdateOne(ctx, bson.M{"_id": promptID}, bson.M{"$set": bson.M{"active": true}})
+		return nil, err
+	})
+	if err != nil {
+		return err
+	}
+	invalidatePrompt(persona)
+	return nil
+}
+
+// ActivatePromptVersion makes an existing version the active one, e.g. to
+// roll back.
+func ActivatePromptVersion(c *fiber.Ctx) error {
+	promptID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid prompt ID")
+	}
+
+	collection := database.GetCollection("prompt_templates")
+	var tmpl models.PromptTemplate
+	err = collection.FindOne(context.Background(), bson.M{"_id": promptID}).Decode(&tmpl)
+	if err == mongo.ErrNoDocuments {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Prompt not found")
+	}
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to activate prompt")
+	}
+
+	if err := activatePrompt(tmpl.Persona, tmpl.ID); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to activate prompt")
+	}
+	tmpl.Active = true
+
+	return c.JSON(fiber.Map{
+		"prompt": tmpl,
+	})
+}
+
+// PreviewPrompt renders a template with the given variables without
+// saving it.
+func PreviewPrompt(c *fiber.Ctx) error {
+	var req models.PromptPreviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	lang := req.Language
+	if lang == "" {
+		lang = "en"
+	}
+	rendered, err := services.RenderPrompt(req.Template, services.PromptVars{
+		Language: lang,
+		UserName: req.UserName,
+		Locale:   req.Locale,
+	})
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"rendered": rendered,
+	})
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"log"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// newToken returns a random URL-safe secret for share links and
+// interpreter invitations.
+func newToken() (string, error) {
+	raw := make([]byte, 24)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// tokenHash is how secret tokens are looked up; the tokens themselves are
+// never stored.
+func tokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// CreateShare makes a read-only link to a conversation. The token is only
+// returned here.
+func CreateShare(c *fiber.Ctx) error {
+	var req models.CreateShareRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+		}
+	}
+	if err := utils.Validate.Struct(req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	token, err := newToken()
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create share link")
+	}
+
+	now := time.Now()
+	share := models.ConversationShare{
+		ID:             primitive.NewObjectID(),
+		ConversationID: conversation.ID,
+		UserID:         conversation.UserID,
+		TokenHash:      tokenHash(token),
+		CreatedAt:      now,
+	}
+	if req.ExpiresInHours > 0 {
+		expires := now.Add(time.Duration(req.ExpiresInHours) * time.Hour)
+		share.ExpiresAt = &expires
+	}
+	if _, err := database.GetCollection("conversation_shares").InsertOne(context.Background(), share); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to create share link")
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"share": share,
+		"token": token,
+		"url":   "/api/v1/shared/" + token,
+	})
+}
+
+// GetShares lists a conversation's share links, newest first, including
+// revoked and expired ones.
+func GetShares(c *fiber.Ctx) error {
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "createdAt", Value: -1}})
+	cursor, err := database.GetCollection("conversation_shares").Find(context.Background(), bson.M{"conversationId": conversation.ID}, opts)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch share links")
+	}
+	shares := []models.ConversationShare{}
+	if err := cursor.All(context.Background(), &shares); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode share links")
+	}
+
+	return c.JSON(fiber.Map{
+		"shares": shares,
+	})
+}
+
+// RevokeShare disables a share link for good.
+func RevokeShare(c *fiber.Ctx) error {
+	conversation, status, err := ownConversation(c, false)
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+	shareObjID, err := primitive.ObjectIDFromHex(c.Params("shareId"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid share ID")
+	}
+
+	result, err := database.GetCollection("conversation_shares").UpdateOne(
+		context.Background(),
+		bson.M{"_id": shareObjID, "conversationId": conversation.ID, "revokedAt": nil},
+		bson.M{"$set": bson.M{"revokedAt": time.Now()}},
+	)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to revoke share link")
+	}
+	if result.MatchedCount == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Share link not found")
+	}
+
+	return c.SendStatus(fiber.StatusNoContent)
+}
+
+// GetSharedConversation is the public view of a share link: the active
+// branch with personal data, including the owner's name, redacted, as
+// ?format=json (default), md or pdf. Each view served is counted. Revoked
+// and expired links, and links to conversations in the trash, are not
+// found.
+func GetSharedConversation(c *fiber.Ctx) error {
+	format, loc, err := exportOptions(c, "json")
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	now := time.Now()
+	live := bson.M{
+		"tokenHash": tokenHash(c.Params("token")),
+		"revokedAt": nil,
+		"$or":       bson.A{bson.M{"expiresAt": nil}, bson.M{"expiresAt": bson.M{"$gt": now}}},
+	}
+	var share models.ConversationShare
+	err = database.GetCollection("conversation_shares").FindOne(context.Background(), live).Decode(&share)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Share link not found or expired")
+	}
+
+	var conversation models.Conversation
+	err = database.GetCollection("conversations").FindOne(
+		context.Background(),
+		bson.M{"_id": share.ConversationID, "deletedAt": nil},
+	).Decode(&conversation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Share link not found or expired")
+	}
+	path, err := activePath(&conversation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch messages")
+	}
+
+	// Checked again in case the link was revoked meanwhile
+	live["_id"] = share.ID
+	result, err := database.GetCollection("conversation_shares").UpdateOne(
+		context.Background(),
+		live,
+		bson.M{"$inc": bson.M{"accessCount": 1}, "$set": bson.M{"lastAccessedAt": now}},
+	)
+	if err != nil {
+		log.Printf("Share: failed to count access to share %s: %v", share.ID.Hex(), err)
+	} else if result.MatchedCount == 0 {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Share link not found or expired")
+	}
+
+	export := services.NewConversationExport(&conversation, path, exportLanguage(c, path))
+	export = services.RedactExport(export, userDisplayName(conversation.UserID))
+	c.Set("X-Robots-Tag", "noindex")
+	return sendExport(c, export, format, loc, "inline")
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/developia-II/language-translator-backend/internal/services"
+	"github.com/developia-II/language-translator-backend/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// maxSTTUpload matches Whisper's 25MB file limit.
+const maxSTTUpload = 25 << 20
+
+// hasWhisper reports whether an LLM provider has a transcription model.
+func hasWhisper() bool {
+	llm := services.LLM()
+	return llm != nil && llm.Serves(services.UseTranscribe)
+}
+
+// sttProvider picks the transcription backend. An explicit choice (form
+// field or STT_PROVIDER) wins when it is available; otherwise Whisper is
+// used when a provider can transcribe, except for Igbo which Whisper
+// doesn't support.
+func sttProvider(choice, lang string) services.STTProvider {
+	choice = strings.ToLower(strings.TrimSpace(choice))
+	if choice == "" {
+		choice = strings.ToLower(strings.TrimSpace(os.Getenv("STT_PROVIDER")))
+	}
+	hasHF := strings.TrimSpace(os.Getenv("HF_API_TOKEN")) != ""
+
+	switch {
+	case choice == "mms", !hasWhisper():
+		return services.MMSProvider
+	case choice == "whisper":
+	case baseLang(lang) == "ig" && hasHF:
+		return services.MMSProvider
+	}
+	return services.WhisperProvider(services.LLM())
+}
+
+// sttAvailable reports whether any transcription backend is configured.
+func sttAvailable() bool {
+	return hasWhisper() || strings.TrimSpace(os.Getenv("HF_API_TOKEN")) != ""
+}
+
+// transcribeUpload reads the "audio" multipart field and transcribes it with
+// the provider chosen by the "provider" field. lang is an optional hint.
+func transcribeUpload(c *fiber.Ctx, lang string) (*services.Transcript, int, error) {
+	fh, err := c.FormFile("audio")
+	if err != nil {
+		return nil, fiber.StatusBadRequest, fmt.Errorf("audio file is required")
+	}
+	if fh.Size > maxSTTUpload {
+		return nil, fiber.StatusRequestEntityTooLarge, fmt.Errorf("audio file must be under 25MB")
+	}
+
+	f, err := fh.Open()
+	if err != nil {
+		return nil, fiber.StatusBadRequest, fmt.Errorf("failed to read audio file")
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSTTUpload))
+	if err != nil {
+		return nil, fiber.StatusBadRequest, fmt.Errorf("failed to read audio file")
+	}
+
+	if services.AudioContentType(data, fh.Filename) == "" {
+		return nil, fiber.StatusUnsupportedMediaType, fmt.Errorf("audio must be WAV, MP3 or WebM")
+	}
+
+	provider := sttProvider(c.FormValue("provider"), lang)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	transcript, err := provider.Transcribe(ctx, data, fh.Filename, lang)
+	if err != nil {
+		return nil, fiber.StatusBadGateway, fmt.Errorf("STT failed: %w", err)
+	}
+	if transcript.Text == "" {
+		return nil, fiber.StatusUnprocessableEntity, fmt.Errorf("no speech detected")
+	}
+	return transcript, fiber.StatusOK, nil
+}
+
+// STT transcribes an uploaded recording (multipart field "audio", optional
+// "language" hint and "provider" = whisper|mms).
+func STT(c *fiber.Ctx) error {
+	transcript, status, err := transcribeUpload(c, c.FormValue("language"))
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+
+	return c.JSON(fiber.Map{
+		"transcript": transcript,
+	})
+}
@@ -2,8 +2,12 @@ package handlers
 
 import (
 	"context"
+	"encoding/base64"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/developia-II/language-translator-backend/internal/audio"
 	"github.com/developia-II/language-translator-backend/internal/database"
 	"github.com/developia-II/language-translator-backend/internal/models"
 	"github.com/developia-II/language-translator-backend/internal/services"
@@ -23,13 +27,18 @@ func Translate(c *fiber.Ctx) error {
 	if err := utils.Validate.Struct(req); err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
 	}
+	if verdict := moderateInput(c, req.SourceText); verdict.Flagged {
+		return refuseInput(c, verdict)
+	}
 
 	// Get user ID from context
 	userID := c.Locals("userId").(string)
 	userObjID, _ := primitive.ObjectIDFromHex(userID)
 
 	// Call translation service
-	translatedText, err := services.TranslateText(req.SourceText, req.SourceLang, req.TargetLang)
+	ctx, usage := services.WithUsage(context.Background())
+	translatedText, err := services.TranslateText(ctx, req.SourceText, req.SourceLang, req.TargetLang)
+	recordTranslationUsage(userObjID, usage.Total())
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Translation failed: "+err.Error())
 	}
@@ -80,3 +89,143 @@ func GetTranslations(c *fiber.Ctx) error {
 		"translations": translations,
 	})
 }
+
+// GetTranslationAudio speaks a stored translation in its target language so
+// clients can play it with a plain <audio src> link.
+func GetTranslationAudio(c *fiber.Ctx) error {
+	translationObjID, err := primitive.ObjectIDFromHex(c.Params("id"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid translation ID")
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	collection := database.GetCollection("translations")
+	var translation models.Translation
+	err = collection.FindOne(context.Background(), bson.M{"_id": translationObjID, "userId": userObjID}).Decode(&translation)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusNotFound, "Translation not found")
+	}
+
+	// Speech translations keep the exact audio that was played back
+	if translation.AudioID != nil {
+		var clip models.AudioClip
+		err := database.GetCollection("audio_clips").FindOne(context.Background(), bson.M{"_id": *translation.AudioID}).Decode(&clip)
+		if err == nil {
+			return sendAudio(c, clip.Data, clip.ContentType, c.Query("format"), c.QueryInt("sampleRate"), storedAudioCache)
+		}
+		log.Printf("GetTranslationAudio: audio clip %s missing, resynthesizing: %v", translation.AudioID.Hex(), err)
+	}
+
+	return sendSpeech(c, translation.TranslatedText, normalizeLang(translation.TargetLang), c.Query("format"), c.QueryInt("sampleRate"), storedAudioCache)
+}
+
+// TranslateSpeech runs speech-to-speech translation: the uploaded recording
+// ("audio") is transcribed, translated to "targetLang" and spoken back.
+// "sourceLang" is an optional hint; the detected language is used otherwise.
+func TranslateSpeech(c *fiber.Ctx) error {
+	start := time.Now()
+	var timings models.SpeechTranslateTimings
+
+	targetLang := strings.TrimSpace(c.FormValue("targetLang"))
+	if targetLang == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "targetLang is required")
+	}
+	format, err := ttsOutputFormat(c, c.FormValue("format"))
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+
+	// 1) Speech to text
+	stageStart := time.Now()
+	transcript, status, err := transcribeUpload(c, c.FormValue("sourceLang"))
+	if err != nil {
+		return utils.ErrorResponse(c, status, err.Error())
+	}
+	timings.STT = time.Since(stageStart).Milliseconds()
+
+	sourceLang := transcript.Language
+	if sourceLang == "" {
+		sourceLang = baseLang(c.FormValue("sourceLang"))
+	}
+	if sourceLang == "" {
+		return utils.ErrorResponse(c, fiber.StatusUnprocessableEntity, "Could not detect source language; pass sourceLang")
+	}
+
+	if verdict := moderateInput(c, transcript.Text); verdict.Flagged {
+		return refuseInput(c, verdict)
+	}
+
+	// 2) Text translation
+	stageStart = time.Now()
+	translatedText := transcript.Text
+	if baseLang(sourceLang) != baseLang(targetLang) {
+		ctx, usage := services.WithUsage(context.Background())
+		translatedText, err = services.TranslateText(ctx, transcript.Text, sourceLang, targetLang)
+		recordTranslationUsage(userObjID, usage.Total())
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Translation failed: "+err.Error())
+		}
+	}
+	timings.Translate = time.Since(stageStart).Milliseconds()
+
+	translation := models.Translation{
+		ID:             primitive.NewObjectID(),
+		UserID:         userObjID,
+		SourceText:     transcript.Text,
+		TranslatedText: translatedText,
+		SourceLang:     sourceLang,
+		TargetLang:     targetLang,
+		InputMode:      "speech",
+		CreatedAt:      time.Now(),
+	}
+
+	// 3) Text to speech. A TTS failure still returns the text results.
+	stageStart = time.Now()
+	lang := normalizeLang(targetLang)
+	audioBytes, ctype, _, ttsErr := speak(services.PlainSpeech(translatedText), lang)
+	if ttsErr == nil && format != "" {
+		audioBytes, ctype, ttsErr = audio.Process(audioBytes, ctype, ttsProcessOptions(format, 0))
+	}
+	timings.TTS = time.Since(stageStart).Milliseconds()
+
+	if ttsErr == nil {
+		clip := models.AudioClip{
+			ID:          primitive.NewObjectID(),
+			UserID:      userObjID,
+			ContentType: ctype,
+			Data:        audioBytes,
+			Language:    lang,
+			Text:        translatedText,
+			CreatedAt:   time.Now(),
+		}
+		if _, err := database.GetCollection("audio_clips").InsertOne(context.Background(), clip); err != nil {
+			log.Printf("TranslateSpeech: failed to save audio clip: %v", err)
+		} else {
+			translation.AudioID = &clip.ID
+		}
+	}
+
+	if _, err := database.GetCollection("translations").InsertOne(context.Background(), translation); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to save translation")
+	}
+	timings.Total = time.Since(start).Milliseconds()
+
+	resp := fiber.Map{
+		"translation": translation,
+		"transcript":  transcript,
+		"timings":     timings,
+	}
+	if ttsErr != nil {
+		resp["audioError"] = "TTS failed: " + ttsErr.Error()
+	} else {
+		resp["audio"] = base64.StdEncoding.EncodeToString(audioBytes)
+		resp["contentType"] = ctype
+		resp["audioUrl"] = "/api/v1/translations/" + translation.ID.Hex() + "/audio"
+	}
+	return c.JSON(resp)
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/developia-II/language-translator-backend/internal/audio"
 	"github.com/developia-II/language-translator-backend/internal/services"
 	"github.com/developia-II/language-translator-backend/utils"
 	"github.com/gofiber/fiber/v2"
@@ -13,6 +17,65 @@ import (
 type ttsReq struct {
 	Text string `json:"text"`
 	Lang string `json:"lang"`
+	// Format selects post-processed output: "wav", "pcm" or "original".
+	// When empty the Accept header is used instead.
+	Format     string `json:"format,omitempty"`
+	SampleRate int    `json:"sampleRate,omitempty"`
+}
+
+// ttsOutputFormat picks the output format from the request body, falling
+// back to the Accept header. "" means return provider audio untouched.
+func ttsOutputFormat(c *fiber.Ctx, format string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "wav":
+		return audio.FormatWAV, nil
+	case "pcm", "l16":
+		return audio.FormatPCM, nil
+	case "original":
+		return "", nil
+	case "":
+	default:
+		return "", fmt.Errorf("unsupported format %q (use wav, pcm or original)", format)
+	}
+
+	// Only honour Accept when it names an audio type; browsers send */*.
+	if !strings.Contains(strings.ToLower(c.Get(fiber.HeaderAccept)), "audio/") {
+		return "", nil
+	}
+	switch c.Accepts("audio/wav", "audio/x-wav", "audio/wave", "audio/L16") {
+	case "audio/wav", "audio/x-wav", "audio/wave":
+		return audio.FormatWAV, nil
+	case "audio/L16":
+		return audio.FormatPCM, nil
+	}
+	return "", nil
+}
+
+// ttsProcessOptions builds the post-processing settings from env:
+// TTS_TARGET_LUFS (default -16), TTS_SAMPLE_RATE (default 24000 for wav,
+// 16000 for pcm) and TTS_TRIM_SILENCE (default true).
+func ttsProcessOptions(format string, sampleRate int) audio.Options {
+	opts := audio.Options{
+		Format:     format,
+		Mono:       true,
+		TargetLUFS: -16,
+		Trim:       os.Getenv("TTS_TRIM_SILENCE") != "false",
+	}
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("TTS_TARGET_LUFS")), 64); err == nil {
+		opts.TargetLUFS = v
+	}
+
+	opts.SampleRate = 24000
+	if format == audio.FormatPCM {
+		opts.SampleRate = 16000
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("TTS_SAMPLE_RATE"))); err == nil && v > 0 {
+		opts.SampleRate = v
+	}
+	if sampleRate >= 8000 && sampleRate <= 48000 {
+		opts.SampleRate = sampleRate
+	}
+	return opts
 }
 
 func normalizeLang(l string) string {
@@ -38,53 +101,140 @@ func normalizeLang(l string) string {
 	}
 }
 
-func TTS(c *fiber.Ctx) error {
-	var req ttsReq
-	if err := c.BodyParser(&req); err != nil {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+// ttsProvider picks the provider for lang.
+// Priority: eSpeak > Google > ElevenLabs (YO/IG/HA) > Hugging Face MMS
+func ttsProvider(lang string) services.TTSProvider {
+	if os.Getenv("USE_ESPEAK") == "true" {
+		return services.ESpeakProvider
 	}
-	if strings.TrimSpace(req.Text) == "" {
-		return utils.ErrorResponse(c, fiber.StatusBadRequest, "text is required")
+	if services.GoogleTTSConfigured() {
+		return services.GoogleProvider
 	}
+	if ak := os.Getenv("ELEVENLABS_API_KEY"); strings.TrimSpace(ak) != "" {
+		// Only route certain languages to ElevenLabs if API key is present;
+		// fall through to HF for other languages even when the key is set
+		switch baseLang(lang) {
+		case "yo", "ig", "ha":
+			return services.ElevenLabsProvider
+		}
+	}
+	return services.HuggingFaceProvider
+}
 
-	lang := normalizeLang(req.Lang)
+// baseLang strips the region from a language tag: "yo-NG" -> "yo".
+func baseLang(lang string) string {
+	low := strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexByte(low, '-'); i > 0 {
+		return low[:i]
+	}
+	return low
+}
 
-	var audio []byte
-	var ctype string
-	var err error
+var (
+	ttsCacheOnce sync.Once
+	ttsCache     *services.AudioCache
+)
 
-	// Priority: eSpeak > Google > ElevenLabs (YO/IG/HA) > Hugging Face MMS
-	if os.Getenv("USE_ESPEAK") == "true" {
-		log.Printf("TTS handler: provider=eSpeak lang=%s", lang)
-		audio, ctype, err = services.ESpeakTTS(req.Text, lang)
-	} else if os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" {
-		// audio, ctype, err = services.GoogleCloudTTS(req.Text, lang)
-	} else if ak := os.Getenv("ELEVENLABS_API_KEY"); strings.TrimSpace(ak) != "" {
-		// Only route certain languages to ElevenLabs if API key is present
-		low := strings.ToLower(lang)
-		base := low
-		if i := strings.IndexByte(low, '-'); i > 0 {
-			base = low[:i]
-		}
-		switch base {
-		case "yo", "ig", "ha":
-			log.Printf("TTS handler: provider=ElevenLabs lang=%s", lang)
-			audio, ctype, err = services.ElevenLabsTTS(req.Text, lang)
-		default:
-			// fall through to HF for other languages even when ElevenLabs key is set
-			log.Printf("TTS handler: provider=HuggingFace (fallback from ElevenLabs) lang=%s", lang)
-			audio, ctype, err = services.SynthesizeTTS(req.Text, lang)
+// audioCache returns the shared TTS cache, sized by TTS_CACHE_MB (default 64).
+func audioCache() *services.AudioCache {
+	ttsCacheOnce.Do(func() {
+		mb := 64
+		if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv("TTS_CACHE_MB"))); err == nil && v >= 0 {
+			mb = v
 		}
+		ttsCache = services.NewAudioCache(mb << 20)
+	})
+	return ttsCache
+}
+
+// speak applies the pronunciation lexicon for lang and synthesizes doc with
+// the selected provider, reusing cached audio when the same rewritten text
+// was spoken before. The rewritten document is returned for previews.
+func speak(doc *services.SpeechDocument, lang string) ([]byte, string, *services.SpeechDocument, error) {
+	provider := ttsProvider(lang)
+
+	lex, err := lexiconFor(lang)
+	if err != nil {
+		// A broken lexicon shouldn't take TTS down with it
+		log.Printf("TTS handler: lexicon unavailable for lang=%s: %v", lang, err)
 	} else {
-		log.Printf("TTS handler: provider=HuggingFace lang=%s", lang)
-		audio, ctype, err = services.SynthesizeTTS(req.Text, lang)
+		doc = lex.Rewrite(doc, provider.SSML == services.SSMLFull)
 	}
 
+	key := services.AudioCacheKey(provider.Name, lang, strconv.FormatBool(doc.IsSSML), doc.SSML())
+	if audioBytes, ctype, ok := audioCache().Get(key); ok {
+		log.Printf("TTS handler: cache hit provider=%s lang=%s", provider.Name, lang)
+		return audioBytes, ctype, doc, nil
+	}
+
+	log.Printf("TTS handler: provider=%s lang=%s ssml=%t", provider.Name, lang, doc.IsSSML)
+	audioBytes, ctype, err := services.Speak(provider, doc, lang)
+	if err != nil {
+		return nil, "", doc, err
+	}
+	audioCache().Put(key, audioBytes, ctype)
+	return audioBytes, ctype, doc, nil
+}
+
+// storedAudioCache lets browsers keep audio of stored text, which never
+// changes.
+const storedAudioCache = "private, max-age=86400"
+
+// sendSpeech synthesizes text in lang and writes the audio response,
+// post-processed when the client asked for a specific format. cacheControl
+// is only sent with audio, never with an error.
+func sendSpeech(c *fiber.Ctx, text, lang, format string, sampleRate int, cacheControl string) error {
+	format, err := ttsOutputFormat(c, format)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	// Text wrapped in <speak> is parsed once as SSML; providers either get it
+	// passed through or have it emulated in services.Speak.
+	doc, err := services.ParseSpeech(text)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+
+	audioBytes, ctype, _, err := speak(doc, lang)
 	if err != nil {
 		return utils.ErrorResponse(c, fiber.StatusBadGateway, "TTS failed: "+err.Error())
 	}
+	return sendProcessedAudio(c, audioBytes, ctype, format, sampleRate, cacheControl)
+}
+
+// sendAudio writes already synthesized audio, honouring the requested format.
+func sendAudio(c *fiber.Ctx, audioBytes []byte, ctype, format string, sampleRate int, cacheControl string) error {
+	format, err := ttsOutputFormat(c, format)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, err.Error())
+	}
+	return sendProcessedAudio(c, audioBytes, ctype, format, sampleRate, cacheControl)
+}
+
+func sendProcessedAudio(c *fiber.Ctx, audioBytes []byte, ctype, format string, sampleRate int, cacheControl string) error {
+	if format != "" {
+		var err error
+		audioBytes, ctype, err = audio.Process(audioBytes, ctype, ttsProcessOptions(format, sampleRate))
+		if err != nil {
+			return utils.ErrorResponse(c, fiber.StatusBadGateway, "Audio processing failed: "+err.Error())
+		}
+	}
 
 	c.Set("Content-Type", ctype)
-	c.Set("Cache-Control", "no-store")
-	return c.Send(audio)
+	c.Set("Cache-Control", cacheControl)
+	c.Vary(fiber.HeaderAccept)
+	return c.Send(audioBytes)
+}
+
+func TTS(c *fiber.Ctx) error {
+	var req ttsReq
+	if err := c.BodyParser(&req); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "Invalid request body")
+	}
+	if strings.TrimSpace(req.Text) == "" {
+		return utils.ErrorResponse(c, fiber.StatusBadRequest, "text is required")
+	}
+
+	return sendSpeech(c, req.Text, normalizeLang(req.Lang), req.Format, req.SampleRate, "no-store")
 }
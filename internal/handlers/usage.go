@@ -0,0 +1,269 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/developia-II/language-translator-backend/internal/database"
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/developia-II/language-translator-backend/utils"
+)
+
+// tokenQuota is a role's token allowance; 0 means unlimited.
+type tokenQuota struct {
+	Daily   int
+	Monthly int
+}
+
+// defaultQuotas apply when TOKEN_QUOTA_<ROLE>_DAILY / _MONTHLY are not set.
+// Roles without defaults get the user quota.
+var defaultQuotas = map[string]tokenQuota{
+	"user":  {Daily: 100_000, Monthly: 2_000_000},
+	"admin": {},
+}
+
+func quotaFor(role string) tokenQuota {
+	role = strings.ToLower(strings.TrimSpace(role))
+	if role == "" {
+		role = "user"
+	}
+	q, ok := defaultQuotas[role]
+	if !ok {
+		q = defaultQuotas["user"]
+	}
+	prefix := "TOKEN_QUOTA_" + strings.ToUpper(role) + "_"
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(prefix + "DAILY"))); err == nil {
+		q.Daily = v
+	}
+	if v, err := strconv.Atoi(strings.TrimSpace(os.Getenv(prefix + "MONTHLY"))); err == nil {
+		q.Monthly = v
+	}
+	return q
+}
+
+// quotaPeriods returns the start of the current UTC day and month and when
+// each resets.
+func quotaPeriods(now time.Time) (dayStart, dayReset, monthStart, monthReset time.Time) {
+	now = now.UTC()
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return dayStart, dayStart.AddDate(0, 0, 1), monthStart, monthStart.AddDate(0, 1, 0)
+}
+
+// quotaStatus sums the user's tokens for the current day and month.
+func quotaStatus(userObjID primitive.ObjectID, role string) (daily, monthly models.QuotaStatus, err error) {
+	dayStart, dayReset, monthStart, monthReset := quotaPeriods(time.Now())
+	quota := quotaFor(role)
+	daily = models.QuotaStatus{Period: "daily", Limit: quota.Daily, ResetAt: dayReset}
+	monthly = models.QuotaStatus{Period: "monthly", Limit: quota.Monthly, ResetAt: monthReset}
+
+	pipeline := []bson.M{
+		{"$match": bson.M{"userId": userObjID, "createdAt": bson.M{"$gte": monthStart}}},
+		{"$group": bson.M{
+			"_id":     nil,
+			"monthly": bson.M{"$sum": "$totalTokens"},
+			"daily": bson.M{"$sum": bson.M{"$cond": bson.A{
+				bson.M{"$gte": bson.A{"$createdAt", dayStart}}, "$totalTokens", 0,
+			}}},
+		}},
+	}
+	cursor, err := database.GetCollection("usage").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return daily, monthly, err
+	}
+	var sums []struct {
+		Daily   int `bson:"daily"`
+		Monthly int `bson:"monthly"`
+	}
+	if err := cursor.All(context.Background(), &sums); err != nil {
+		return daily, monthly, err
+	}
+	if len(sums) > 0 {
+		daily.Used = sums[0].Daily
+		monthly.Used = sums[0].Monthly
+	}
+	return daily, monthly, nil
+}
+
+// QuotaMiddleware rejects LLM requests, and translations that may fall
+// back to the LLM, with 429 once the user's daily or monthly token quota
+// is used up. The request that crosses the limit is
+// still answered, since its cost is only known afterwards.
+func QuotaMiddleware(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	role, _ := c.Locals("role").(string)
+
+	daily, monthly, err := quotaStatus(userObjID, role)
+	if err != nil {
+		// Usage accounting must not take chat down with it
+		log.Printf("Quota: failed to check usage of %s: %v", userID, err)
+		return c.Next()
+	}
+
+	for _, q := range []models.QuotaStatus{monthly, daily} {
+		if q.Limit > 0 && q.Used >= q.Limit {
+			retry := int(time.Until(q.ResetAt).Seconds()) + 1
+			c.Set("Retry-After", strconv.Itoa(retry))
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":   fmt.Sprintf("Token quota used up (%d tokens %s); resets at %s", q.Limit, q.Period, q.ResetAt.Format(time.RFC3339)),
+				"quota":   q,
+				"resetAt": q.ResetAt,
+			})
+		}
+	}
+	return c.Next()
+}
+
+// recordUsage charges the conversation's owner for an answer's tokens.
+// messageID is nil when no answer was produced.
+func recordUsage(conversation *models.Conversation, messageID *primitive.ObjectID, usage *models.TokenUsage) {
+	insertUsage(models.UsageRecord{
+		UserID:         conversation.UserID,
+		ConversationID: conversation.ID,
+		MessageID:      messageID,
+	}, usage)
+}
+
+// recordTranslationUsage charges a user for the tokens a translation
+// outside chat spent, which is only when it fell back to the LLM.
+func recordTranslationUsage(userID primitive.ObjectID, usage *models.TokenUsage) {
+	insertUsage(models.UsageRecord{UserID: userID}, usage)
+}
+
+func insertUsage(record models.UsageRecord, usage *models.TokenUsage) {
+	if usage == nil {
+		return
+	}
+	record.ID = primitive.NewObjectID()
+	record.TokenUsage = *usage
+	record.CreatedAt = time.Now()
+	if _, err := database.GetCollection("usage").InsertOne(context.Background(), record); err != nil {
+		log.Printf("Quota: failed to record usage for user %s: %v", record.UserID.Hex(), err)
+	}
+}
+
+// GetUsage returns the user's token use against their quotas
+func GetUsage(c *fiber.Ctx) error {
+	userID := c.Locals("userId").(string)
+	userObjID, _ := primitive.ObjectIDFromHex(userID)
+	role, _ := c.Locals("role").(string)
+
+	daily, monthly, err := quotaStatus(userObjID, role)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch usage")
+	}
+
+	return c.JSON(fiber.Map{
+		"daily":   daily,
+		"monthly": monthly,
+	})
+}
+
+// metricsSince parses ?range=Nd (default 30d, at most 180 days).
+func metricsSince(c *fiber.Ctx) time.Time {
+	days := 30
+	if v, err := strconv.Atoi(strings.TrimSuffix(c.Query("range", "30d"), "d")); err == nil && v > 0 && v <= 180 {
+		days = v
+	}
+	return time.Now().AddDate(0, 0, -days)
+}
+
+// GetTokenUsage returns token spend per day over a range (default 30d)
+func GetTokenUsage(c *fiber.Ctx) error {
+	pipeline := []bson.M{
+		{"$match": bson.M{"createdAt": bson.M{"$gte": metricsSince(c)}}},
+		{"$group": bson.M{
+			"_id":              bson.M{"$dateToString": bson.M{"format": "%Y-%m-%d", "date": "$createdAt"}},
+			"promptTokens":     bson.M{"$sum": "$promptTokens"},
+			"completionTokens": bson.M{"$sum": "$completionTokens"},
+			"totalTokens":      bson.M{"$sum": "$totalTokens"},
+			"costUsd":          bson.M{"$sum": "$costUsd"},
+			"answers":          bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+	cursor, err := database.GetCollection("usage").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch usage")
+	}
+
+	type point struct {
+		Date             string  `json:"date" bson:"_id"`
+		PromptTokens     int     `json:"promptTokens" bson:"promptTokens"`
+		CompletionTokens int     `json:"completionTokens" bson:"completionTokens"`
+		TotalTokens      int     `json:"totalTokens" bson:"totalTokens"`
+		CostUSD          float64 `json:"costUsd" bson:"costUsd"`
+		Answers          int     `json:"answers" bson:"answers"`
+	}
+	series := []point{}
+	if err := cursor.All(context.Background(), &series); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode usage")
+	}
+
+	return c.JSON(fiber.Map{
+		"series": series,
+	})
+}
+
+// GetTokenUsageByUser returns the users with the highest token spend over
+// a range (default 30d), at most ?limit= (default 20)
+func GetTokenUsageByUser(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 20)
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	pipeline := []bson.M{
+		{"$match": bson.M{"createdAt": bson.M{"$gte": metricsSince(c)}}},
+		{"$group": bson.M{
+			"_id":         "$userId",
+			"totalTokens": bson.M{"$sum": "$totalTokens"},
+			"costUsd":     bson.M{"$sum": "$costUsd"},
+			"answers":     bson.M{"$sum": 1},
+		}},
+		{"$sort": bson.M{"totalTokens": -1}},
+		{"$limit": limit},
+		{"$lookup": bson.M{"from": "users", "localField": "_id", "foreignField": "_id", "as": "user"}},
+		{"$unwind": bson.M{"path": "$user", "preserveNullAndEmptyArrays": true}},
+	}
+	cursor, err := database.GetCollection("usage").Aggregate(context.Background(), pipeline)
+	if err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to fetch usage")
+	}
+
+	var rows []struct {
+		UserID      primitive.ObjectID `bson:"_id"`
+		TotalTokens int                `bson:"totalTokens"`
+		CostUSD     float64            `bson:"costUsd"`
+		Answers     int                `bson:"answers"`
+		User        models.User        `bson:"user"`
+	}
+	if err := cursor.All(context.Background(), &rows); err != nil {
+		return utils.ErrorResponse(c, fiber.StatusInternalServerError, "Failed to decode usage")
+	}
+
+	users := make([]fiber.Map, len(rows))
+	for i, r := range rows {
+		users[i] = fiber.Map{
+			"userId":      r.UserID,
+			"name":        r.User.Name,
+			"email":       r.User.Email,
+			"role":        r.User.Role,
+			"totalTokens": r.TotalTokens,
+			"costUsd":     r.CostUSD,
+			"answers":     r.Answers,
+		}
+	}
+	return c.JSON(fiber.Map{
+		"users": users,
+	})
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AudioClip is synthesized audio kept alongside the record that produced it
+// (e.g. a speech-to-speech translation) so it can be replayed as-is.
+type AudioClip struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID      primitive.ObjectID `json:"userId" bson:"userId"`
+	ContentType string             `json:"contentType" bson:"contentType"`
+	Data        []byte             `json:"-" bson:"data"`
+	Language    string             `json:"language" bson:"language"`
+	Text        string             `json:"text" bson:"text"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+}
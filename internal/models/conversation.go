@@ -7,26 +7,165 @@ import (
 )
 
 type Conversation struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID    primitive.ObjectID `json:"userId" bson:"userId"`
-	Title     string             `json:"title" bson:"title"`
-	Messages  []Message          `json:"messages" bson:"messages"`
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
-	UpdatedAt time.Time          `json:"updatedAt" bson:"updatedAt"`
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"userId" bson:"userId"`
+	Title  string             `json:"title" bson:"title"`
+	// Persona picks the system prompt; empty means the medical assistant.
+	Persona string `json:"persona,omitempty" bson:"persona,omitempty"`
+	// Messages are stored in the messages collection and only filled in
+	// for responses that include them.
+	Messages     []Message `json:"messages,omitempty" bson:"-"`
+	MessageCount int       `json:"messageCount" bson:"messageCount"`
+	LastMessage  string    `json:"lastMessage,omitempty" bson:"lastMessage,omitempty"` // Preview of the newest message
+	Pinned       bool      `json:"pinned" bson:"pinned"`
+	Archived     bool      `json:"archived" bson:"archived"`
+	// DeletedAt marks a conversation as in the trash; it can be restored.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	// ActiveLeafID is the last message of the branch the user is viewing;
+	// the history is the chain of parents leading to it.
+	ActiveLeafID *primitive.ObjectID `json:"activeLeafId,omitempty" bson:"activeLeafId,omitempty"`
+	// Summary condenses the history up to and including SummarizedThrough
+	// so only recent turns are sent to the model verbatim. It is ignored on
+	// branches that do not contain that message.
+	Summary           string              `json:"summary,omitempty" bson:"summary,omitempty"`
+	SummarizedThrough *primitive.ObjectID `json:"summarizedThrough,omitempty" bson:"summarizedThrough,omitempty"`
+	// Version is bumped on every write; metadata updates only apply when
+	// the version they read is still current.
+	Version   int64     `json:"version" bson:"version"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt" bson:"updatedAt"`
 }
 
+// Message is one chat turn, stored in the messages collection.
 type Message struct {
-	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	Role      string             `json:"role" bson:"role"` // "user" or "assistant"
-	Content   string             `json:"content" bson:"content"`
-	Language  string             `json:"language" bson:"language"` // Language code (en, yo, ig, ha)
-	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ConversationID primitive.ObjectID `json:"conversationId" bson:"conversationId"`
+	// ParentID is the message this one follows; nil for the first message.
+	// Edits and regenerations add siblings, forking the history.
+	ParentID *primitive.ObjectID `json:"parentId,omitempty" bson:"parentId,omitempty"`
+	Role     string              `json:"role" bson:"role"` // "user" or "assistant"
+	Content  string              `json:"content" bson:"content"`
+	Language string              `json:"language" bson:"language"`                   // Language code (en, yo, ig, ha)
+	Partial  bool                `json:"partial,omitempty" bson:"partial,omitempty"` // Streaming stopped before the model finished
+	// EnglishContent is the English side of a bridged turn: what the model
+	// was asked, or what it answered before translation into Language.
+	EnglishContent string `json:"englishContent,omitempty" bson:"englishContent,omitempty"`
+	// Severity and EmergencyCategory are set on the user message that
+	// raised a red flag and on the answer to it.
+	Severity          string `json:"severity,omitempty" bson:"severity,omitempty"`
+	EmergencyCategory string `json:"emergencyCategory,omitempty" bson:"emergencyCategory,omitempty"`
+	// Triage holds the parsed answer in triage mode, RawOutput the model's
+	// text it was parsed from; Content is then a readable rendering.
+	Triage    *Triage `json:"triage,omitempty" bson:"triage,omitempty"`
+	RawOutput string  `json:"rawOutput,omitempty" bson:"rawOutput,omitempty"`
+	// Prompt is the system prompt version behind an assistant message.
+	Prompt *PromptRef `json:"prompt,omitempty" bson:"prompt,omitempty"`
+	// Usage is the tokens spent producing an assistant message.
+	Usage *TokenUsage `json:"usage,omitempty" bson:"usage,omitempty"`
+	// Parts records the tool calls and results behind an answer, in order.
+	Parts []MessagePart `json:"parts,omitempty" bson:"parts,omitempty"`
+	// Citations are the knowledge base passages the answer was given.
+	Citations []Citation `json:"citations,omitempty" bson:"citations,omitempty"`
+	CreatedAt time.Time  `json:"createdAt" bson:"createdAt"`
+	// Siblings lists the alternatives to this message (itself included),
+	// oldest first, when there is more than one.
+	Siblings []primitive.ObjectID `json:"siblings,omitempty" bson:"-"`
+}
+
+// Message part types.
+const (
+	PartToolCall   = "tool_call"
+	PartToolResult = "tool_result"
+	PartText       = "text"
+)
+
+// MessagePart is one step of an answer: a tool the model called, what the
+// tool returned, or the final text.
+type MessagePart struct {
+	Type       string `json:"type" bson:"type"`
+	ToolCallID string `json:"toolCallId,omitempty" bson:"toolCallId,omitempty"`
+	Name       string `json:"name,omitempty" bson:"name,omitempty"`
+	Arguments  string `json:"arguments,omitempty" bson:"arguments,omitempty"` // JSON
+	Result     string `json:"result,omitempty" bson:"result,omitempty"`       // JSON
+	Error      string `json:"error,omitempty" bson:"error,omitempty"`
+	Text       string `json:"text,omitempty" bson:"text,omitempty"`
+}
+
+// ConversationSummary is the lightweight list view of a conversation.
+type ConversationSummary struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id"`
+	Title        string             `json:"title" bson:"title"`
+	LastMessage  string             `json:"lastMessage" bson:"lastMessage"`
+	MessageCount int                `json:"messageCount" bson:"messageCount"`
+	Pinned       bool               `json:"pinned" bson:"pinned"`
+	Archived     bool               `json:"archived" bson:"archived"`
+	DeletedAt    *time.Time         `json:"deletedAt,omitempty" bson:"deletedAt,omitempty"`
+	UpdatedAt    time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+// ConversationUpdateRequest changes conversation metadata; omitted fields
+// are left alone. When Version is set the update fails with 409 if the
+// conversation has changed since the client read it.
+type ConversationUpdateRequest struct {
+	Title    *string `json:"title,omitempty" validate:"omitempty,min=1,max=100"`
+	Pinned   *bool   `json:"pinned,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+	Version  *int64  `json:"version,omitempty"`
+}
+
+// EditMessageRequest replaces a user message, forking the conversation.
+type EditMessageRequest struct {
+	Message  string `json:"message" validate:"required"`
+	Language string `json:"language,omitempty"` // Defaults to the edited message's language
+	Mode     string `json:"mode,omitempty" validate:"omitempty,oneof=text triage"`
+}
+
+// RegenerateRequest asks for a new answer to the last user message.
+type RegenerateRequest struct {
+	Language string `json:"language,omitempty"`                                    // Defaults to the replaced answer's language
+	Mode     string `json:"mode,omitempty" validate:"omitempty,oneof=text triage"` // Defaults to the replaced answer's mode
+}
+
+// SwitchBranchRequest makes the branch through MessageID the active one.
+type SwitchBranchRequest struct {
+	MessageID string `json:"messageId" validate:"required"`
 }
 
 type ChatRequest struct {
 	ConversationID string `json:"conversationId,omitempty"` // Empty for new conversation
 	Message        string `json:"message" validate:"required"`
 	Language       string `json:"language" validate:"required"` // Target language for response
+	// Mode "triage" returns a structured Triage with the answer
+	Mode string `json:"mode,omitempty" validate:"omitempty,oneof=text triage"`
+	// Persona switches the conversation's assistant persona
+	Persona string `json:"persona,omitempty"`
+	// Locale fills the {{.Locale}} prompt variable, e.g. "yo-NG"
+	Locale string `json:"locale,omitempty"`
+}
+
+// ConversationExport is a conversation's active branch as exported for a
+// patient to share, e.g. with their doctor.
+type ConversationExport struct {
+	ID         primitive.ObjectID `json:"id"`
+	Title      string             `json:"title"`
+	Messages   []ExportedMessage  `json:"messages"`
+	Disclaimer string             `json:"disclaimer"`
+	ExportedAt time.Time          `json:"exportedAt"`
+}
+
+// ExportedMessage is a message without the internals (prompt, usage, tool
+// calls) that only matter to the app.
+type ExportedMessage struct {
+	Role              string     `json:"role"`
+	Content           string     `json:"content"`
+	Language          string     `json:"language"`
+	LanguageName      string     `json:"languageName"`
+	Partial           bool       `json:"partial,omitempty"`
+	Severity          string     `json:"severity,omitempty"`
+	EmergencyCategory string     `json:"emergencyCategory,omitempty"`
+	Triage            *Triage    `json:"triage,omitempty"`
+	Citations         []Citation `json:"citations,omitempty"`
+	CreatedAt         time.Time  `json:"createdAt"`
 }
 
 type ChatResponse struct {
@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Emergency severities, lowest to highest.
+const (
+	SeverityNone     = ""
+	SeverityLow      = "low"      // Worth seeing a clinician soon
+	SeverityHigh     = "high"     // Needs urgent care today
+	SeverityCritical = "critical" // Possibly life-threatening, act now
+)
+
+// SeverityRank orders severities so the highest can be picked.
+func SeverityRank(severity string) int {
+	switch severity {
+	case SeverityLow:
+		return 1
+	case SeverityHigh:
+		return 2
+	case SeverityCritical:
+		return 3
+	}
+	return 0
+}
+
+// RedFlagMatch is one red-flag phrase found in a message.
+type RedFlagMatch struct {
+	Phrase   string `json:"phrase" bson:"phrase"`
+	Language string `json:"language" bson:"language"`
+	Category string `json:"category" bson:"category"`
+	Severity string `json:"severity" bson:"severity"`
+}
+
+// EmergencyAssessment is the verdict on one message. Source is "keywords",
+// "classifier" or both joined with "+".
+type EmergencyAssessment struct {
+	Severity string         `json:"severity" bson:"severity"`
+	Category string         `json:"category,omitempty" bson:"category,omitempty"`
+	Matches  []RedFlagMatch `json:"matches,omitempty" bson:"matches,omitempty"`
+	Source   string         `json:"source,omitempty" bson:"source,omitempty"`
+}
+
+// EmergencyEvent records a flagged chat message for follow-up; stored in
+// the emergency_events collection.
+type EmergencyEvent struct {
+	ID                  primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID              primitive.ObjectID `json:"userId" bson:"userId"`
+	ConversationID      primitive.ObjectID `json:"conversationId" bson:"conversationId"`
+	MessageID           primitive.ObjectID `json:"messageId" bson:"messageId"`
+	Text                string             `json:"text" bson:"text"`
+	Language            string             `json:"language" bson:"language"`
+	EmergencyAssessment `bson:",inline"`
+	WebhookStatus       string    `json:"webhookStatus,omitempty" bson:"webhookStatus,omitempty"` // "sent", "failed" or empty when not configured
+	CreatedAt           time.Time `json:"createdAt" bson:"createdAt"`
+}
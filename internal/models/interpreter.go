@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// InterpreterSession is a live two-party interpreted conversation. Each
+// participant speaks their own language and every utterance is translated
+// into the other's. Utterances live in their own collection and are only
+// loaded with the transcript.
+type InterpreterSession struct {
+	ID            primitive.ObjectID       `json:"id" bson:"_id,omitempty"`
+	CreatedBy     primitive.ObjectID       `json:"createdBy" bson:"createdBy"`
+	Title         string                   `json:"title" bson:"title"`
+	JoinTokenHash string                   `json:"-" bson:"joinTokenHash"` // invitation for the second participant
+	Participants  []InterpreterParticipant `json:"participants" bson:"participants"`
+	Utterances    []Utterance              `json:"utterances,omitempty" bson:"-"` // kept in interpreter_utterances
+	Status        string                   `json:"status" bson:"status"`          // "open" or "closed"
+	CreatedAt     time.Time                `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time                `json:"updatedAt" bson:"updatedAt"`
+}
+
+type InterpreterParticipant struct {
+	UserID   primitive.ObjectID `json:"userId" bson:"userId"`
+	Name     string             `json:"name" bson:"name"`
+	Language string             `json:"language" bson:"language"` // Language code (en, yo, ig, ha)
+	JoinedAt time.Time          `json:"joinedAt" bson:"joinedAt"`
+}
+
+// Utterance is one turn in an interpreter session, stored with every
+// translation that was delivered.
+type Utterance struct {
+	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	SessionID    primitive.ObjectID `json:"sessionId" bson:"sessionId"`
+	SpeakerID    primitive.ObjectID `json:"speakerId" bson:"speakerId"`
+	Language     string             `json:"language" bson:"language"`
+	Text         string             `json:"text" bson:"text"`
+	InputMode    string             `json:"inputMode" bson:"inputMode"` // "text" or "speech"
+	Translations map[string]string  `json:"translations" bson:"translations"`
+	CreatedAt    time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+type InterpreterJoinRequest struct {
+	Language  string `json:"language" validate:"required"`
+	Title     string `json:"title,omitempty" validate:"max=100"`
+	JoinToken string `json:"joinToken,omitempty"` // from CreateInterpreterSession; needed to join
+}
@@ -0,0 +1,61 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Knowledge document formats.
+const (
+	KnowledgeFormatMarkdown = "markdown"
+	KnowledgeFormatText     = "text" // plain text, e.g. extracted from a PDF
+)
+
+// KnowledgeDocument is a vetted source, such as a health-ministry leaflet,
+// that chat answers are grounded in. Its text lives in KnowledgeChunks.
+type KnowledgeDocument struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Title          string             `json:"title" bson:"title"`
+	Source         string             `json:"source,omitempty" bson:"source,omitempty"` // Publisher or URL shown in citations
+	Language       string             `json:"language" bson:"language"`
+	Format         string             `json:"format" bson:"format"`
+	ChunkCount     int                `json:"chunkCount" bson:"chunkCount"`
+	EmbeddingModel string             `json:"embeddingModel" bson:"embeddingModel"`
+	CreatedBy      primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// KnowledgeChunk is a retrievable passage of a document with its embedding.
+type KnowledgeChunk struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	DocumentID primitive.ObjectID `json:"documentId" bson:"documentId"`
+	Index      int                `json:"index" bson:"index"`
+	Heading    string             `json:"heading,omitempty" bson:"heading,omitempty"` // Nearest Markdown heading
+	Text       string             `json:"text" bson:"text"`
+	Embedding  []float32          `json:"-" bson:"embedding"`
+	// Model is the embedding model; only chunks embedded with the current
+	// model are searched.
+	Model     string    `json:"model" bson:"model"`
+	CreatedAt time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+type KnowledgeDocumentRequest struct {
+	Title    string `json:"title" form:"title" validate:"required,max=200"`
+	Source   string `json:"source" form:"source" validate:"max=500"`
+	Language string `json:"language" form:"language"`
+	Format   string `json:"format" form:"format" validate:"omitempty,oneof=markdown text"`
+	Content  string `json:"content" form:"content"`
+}
+
+// Citation points an answer at the document passage it drew on. Number is
+// the [n] marker the model was asked to use.
+type Citation struct {
+	Number     int                `json:"number" bson:"number"`
+	DocumentID primitive.ObjectID `json:"documentId" bson:"documentId"`
+	ChunkID    primitive.ObjectID `json:"chunkId" bson:"chunkId"`
+	Title      string             `json:"title" bson:"title"`
+	Source     string             `json:"source,omitempty" bson:"source,omitempty"`
+	Heading    string             `json:"heading,omitempty" bson:"heading,omitempty"`
+	Score      float64            `json:"score" bson:"score"`
+}
@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LexiconEntry tells TTS how to pronounce a word in a given language.
+// Respelling is substituted into the text for every provider; IPA is only
+// used by providers that accept SSML <phoneme>.
+type LexiconEntry struct {
+	ID            primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Language      string             `json:"language" bson:"language"` // Base language code (en, yo, ig, ha)
+	Grapheme      string             `json:"grapheme" bson:"grapheme"`
+	Respelling    string             `json:"respelling,omitempty" bson:"respelling,omitempty"`
+	IPA           string             `json:"ipa,omitempty" bson:"ipa,omitempty"`
+	CaseSensitive bool               `json:"caseSensitive" bson:"caseSensitive"`
+	Note          string             `json:"note,omitempty" bson:"note,omitempty"`
+	CreatedBy     primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+	CreatedAt     time.Time          `json:"createdAt" bson:"createdAt"`
+	UpdatedAt     time.Time          `json:"updatedAt" bson:"updatedAt"`
+}
+
+type LexiconEntryRequest struct {
+	Language      string `json:"language" validate:"required"`
+	Grapheme      string `json:"grapheme" validate:"required,max=100"`
+	Respelling    string `json:"respelling" validate:"required_without=IPA,max=200"`
+	IPA           string `json:"ipa" validate:"max=200"`
+	CaseSensitive bool   `json:"caseSensitive"`
+	Note          string `json:"note" validate:"max=500"`
+}
+
+type LexiconPreviewRequest struct {
+	Text string `json:"text" validate:"required"`
+	Lang string `json:"lang" validate:"required"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// PromptTemplate is one version of a persona's system prompt; stored in the
+// prompt_templates collection. Editing a persona adds a new version, and
+// exactly one version per persona is active. Template is a Go text/template
+// with {{.Language}}, {{.UserName}} and {{.Locale}}.
+type PromptTemplate struct {
+	ID          primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Persona     string             `json:"persona" bson:"persona"`
+	Name        string             `json:"name" bson:"name"`
+	Description string             `json:"description,omitempty" bson:"description,omitempty"`
+	Version     int                `json:"version" bson:"version"`
+	Template    string             `json:"template" bson:"template"`
+	Active      bool               `json:"active" bson:"active"`
+	CreatedBy   primitive.ObjectID `json:"createdBy" bson:"createdBy"`
+	CreatedAt   time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// PromptTemplateRequest creates a new version of a persona's prompt.
+type PromptTemplateRequest struct {
+	Persona     string `json:"persona" validate:"required,min=2,max=40"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description,omitempty" validate:"max=500"`
+	Template    string `json:"template" validate:"required"`
+	Activate    *bool  `json:"activate,omitempty"` // Defaults to true
+}
+
+// PromptPreviewRequest renders a template without saving it.
+type PromptPreviewRequest struct {
+	Template string `json:"template" validate:"required"`
+	Language string `json:"language,omitempty"`
+	UserName string `json:"userName,omitempty"`
+	Locale   string `json:"locale,omitempty"`
+}
+
+// PromptRef records which prompt produced an answer. Version 0 is the
+// built-in prompt, used when no template has been saved for the persona.
+type PromptRef struct {
+	Persona    string              `json:"persona" bson:"persona"`
+	Version    int                 `json:"version" bson:"version"`
+	TemplateID *primitive.ObjectID `json:"templateId,omitempty" bson:"templateId,omitempty"`
+}
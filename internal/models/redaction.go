@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RedactedEntity is one piece of personal data replaced by a placeholder
+// before text was sent to a third party. The value itself is not kept.
+type RedactedEntity struct {
+	Type        string `json:"type" bson:"type"` // PHONE, EMAIL, ID or NAME
+	Rule        string `json:"rule" bson:"rule"`
+	Placeholder string `json:"placeholder" bson:"placeholder"`
+}
+
+// RedactionLog records what was redacted from one outbound call.
+type RedactionLog struct {
+	ID        primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	Service   string             `json:"service" bson:"service"` // e.g. "llm", "translate", "tts:ElevenLabs"
+	Entities  []RedactedEntity   `json:"entities" bson:"entities"`
+	CreatedAt time.Time          `json:"createdAt" bson:"createdAt"`
+}
+
+// ModerationResult is the verdict of the input moderation check.
+type ModerationResult struct {
+	Flagged  bool     `json:"flagged" bson:"flagged"`
+	Category string   `json:"category,omitempty" bson:"category,omitempty"` // threat, harassment, hate, sexual
+	Matches  []string `json:"matches,omitempty" bson:"matches,omitempty"`   // Blocked phrases found
+	Source   string   `json:"source,omitempty" bson:"source,omitempty"`     // "keywords", "classifier" or both
+}
+
+// ModerationEvent records a refused input. The text is not kept.
+type ModerationEvent struct {
+	ID               primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID           primitive.ObjectID `json:"userId" bson:"userId"`
+	Endpoint         string             `json:"endpoint" bson:"endpoint"`
+	ModerationResult `bson:",inline"`
+	CreatedAt        time.Time `json:"createdAt" bson:"createdAt"`
+}
@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// ConversationShare is a read-only link to a conversation. Only a hash of
+// the token is stored; the token itself is shown once, when the link is
+// created.
+type ConversationShare struct {
+	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	ConversationID primitive.ObjectID `json:"conversationId" bson:"conversationId"`
+	UserID         primitive.ObjectID `json:"userId" bson:"userId"`
+	TokenHash      string             `json:"-" bson:"tokenHash"`
+	// ExpiresAt is nil for links that don't expire.
+	ExpiresAt      *time.Time `json:"expiresAt,omitempty" bson:"expiresAt,omitempty"`
+	RevokedAt      *time.Time `json:"revokedAt,omitempty" bson:"revokedAt,omitempty"`
+	AccessCount    int        `json:"accessCount" bson:"accessCount"`
+	LastAccessedAt *time.Time `json:"lastAccessedAt,omitempty" bson:"lastAccessedAt,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt" bson:"createdAt"`
+}
+
+// CreateShareRequest creates a share link; without ExpiresInHours it lasts
+// until revoked.
+type CreateShareRequest struct {
+	ExpiresInHours int `json:"expiresInHours,omitempty" validate:"omitempty,min=1,max=8760"`
+}
@@ -7,13 +7,15 @@ import (
 )
 
 type Translation struct {
-	ID             primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID         primitive.ObjectID `json:"userId" bson:"userId"`
-	SourceText     string             `json:"sourceText" bson:"sourceText" validate:"required"`
-	TranslatedText string             `json:"translatedText" bson:"translatedText"`
-	SourceLang     string             `json:"sourceLang" bson:"sourceLang" validate:"required"`
-	TargetLang     string             `json:"targetLang" bson:"targetLang" validate:"required"`
-	CreatedAt      time.Time          `json:"createdAt" bson:"createdAt"`
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID  `json:"userId" bson:"userId"`
+	SourceText     string              `json:"sourceText" bson:"sourceText" validate:"required"`
+	TranslatedText string              `json:"translatedText" bson:"translatedText"`
+	SourceLang     string              `json:"sourceLang" bson:"sourceLang" validate:"required"`
+	TargetLang     string              `json:"targetLang" bson:"targetLang" validate:"required"`
+	InputMode      string              `json:"inputMode,omitempty" bson:"inputMode,omitempty"` // "text" (default), "speech" or "image"
+	AudioID        *primitive.ObjectID `json:"audioId,omitempty" bson:"audioId,omitempty"`     // Synthesized target audio, see AudioClip
+	CreatedAt      time.Time           `json:"createdAt" bson:"createdAt"`
 }
 
 type TranslateRequest struct {
@@ -25,3 +27,11 @@ type TranslateRequest struct {
 type TranslateResponse struct {
 	Translation Translation `json:"translation"`
 }
+
+// SpeechTranslateTimings reports how long each pipeline stage took, in ms.
+type SpeechTranslateTimings struct {
+	STT       int64 `json:"stt"`
+	Translate int64 `json:"translate"`
+	TTS       int64 `json:"tts"`
+	Total     int64 `json:"total"`
+}
@@ -0,0 +1,24 @@
+package models
+
+// Chat answer modes.
+const (
+	ChatModeText   = "text"
+	ChatModeTriage = "triage"
+)
+
+// Triage urgency levels, lowest to highest.
+const (
+	UrgencySelfCare  = "self_care"
+	UrgencyRoutine   = "routine"
+	UrgencyUrgent    = "urgent"
+	UrgencyEmergency = "emergency"
+)
+
+// Triage is the structured answer returned in triage mode.
+type Triage struct {
+	PossibleCauses []string `json:"possibleCauses" bson:"possibleCauses"`
+	SelfCare       []string `json:"selfCare" bson:"selfCare"`
+	WarningSigns   []string `json:"warningSigns" bson:"warningSigns"`
+	Urgency        string   `json:"urgency" bson:"urgency"`
+	SeeDoctorIf    []string `json:"seeDoctorIf" bson:"seeDoctorIf"`
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// TokenUsage is what the LLM calls behind one answer consumed. CostUSD is
+// estimated from the configured per-model prices.
+type TokenUsage struct {
+	PromptTokens     int     `json:"promptTokens" bson:"promptTokens"`
+	CompletionTokens int     `json:"completionTokens" bson:"completionTokens"`
+	TotalTokens      int     `json:"totalTokens" bson:"totalTokens"`
+	CostUSD          float64 `json:"costUsd" bson:"costUsd"`
+	Model            string  `json:"model,omitempty" bson:"model,omitempty"` // Last model used
+	// Estimated is set when a provider did not report usage and it was
+	// counted from the text instead.
+	Estimated bool `json:"estimated,omitempty" bson:"estimated,omitempty"`
+}
+
+// UsageRecord charges a user for the tokens of one answer, or of a
+// translation made outside chat (which has no ConversationID). Quotas and
+// spend metrics are summed from these.
+type UsageRecord struct {
+	ID             primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID         primitive.ObjectID  `json:"userId" bson:"userId"`
+	ConversationID primitive.ObjectID  `json:"conversationId,omitempty" bson:"conversationId,omitempty"`
+	MessageID      *primitive.ObjectID `json:"messageId,omitempty" bson:"messageId,omitempty"`
+	TokenUsage     `bson:",inline"`
+	CreatedAt      time.Time `json:"createdAt" bson:"createdAt"`
+}
+
+// QuotaStatus is a user's consumption against one quota period.
+type QuotaStatus struct {
+	Period  string    `json:"period"` // "daily" or "monthly"
+	Used    int       `json:"used"`
+	Limit   int       `json:"limit"` // 0 means unlimited
+	ResetAt time.Time `json:"resetAt"`
+}
@@ -2,69 +2,202 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
 	"github.com/developia-II/language-translator-backend/internal/models"
 	"github.com/sashabaranov/go-openai"
 )
 
-type GroqService struct {
-	client *openai.Client
-	model  string
+// Chat answers messages with the chat model.
+func (s *LLMService) Chat(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
+	resp, err := s.complete(ctx, UseChat, openai.ChatCompletionRequest{
+		Messages:    messages,
+		Temperature: 0.7,
+		MaxTokens:   1000,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Choices[0].Message.Content, nil
 }
 
-func NewGroqService() *GroqService {
-	apiKey := strings.TrimSpace(os.Getenv("GROQ_API_KEY"))
-	if apiKey == "" {
-		panic("GROQ_API_KEY is not set; please add it to backend/.env or deployment env")
-	}
-	cfg := openai.DefaultConfig(apiKey)
-	cfg.BaseURL = "https://api.groq.com/openai/v1"
+// languageNames spells out language codes for prompts and exports.
+var languageNames = map[string]string{
+	"en":  "English",
+	"yo":  "Yoruba",
+	"ig":  "Igbo",
+	"ha":  "Hausa",
+	"pcm": "Nigerian Pidgin",
+}
 
-	model := strings.TrimSpace(os.Getenv("GROQ_MODEL"))
-	if model == "" {
-		model = "llama-3.1-70b-versatile"
+// LanguageName spells out a language code such as "yo-NG"; unknown codes
+// are returned as they are.
+func LanguageName(code string) string {
+	if n, ok := languageNames[baseLangCode(code)]; ok {
+		return n
 	}
+	return code
+}
 
-	return &GroqService{
-		client: openai.NewClientWithConfig(cfg),
-		model:  model,
+// Translate translates text with the translate model, keeping tone marks
+// and Markdown formatting.
+func (s *LLMService) Translate(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
+	prompt := fmt.Sprintf("Translate the user's text from %s to %s. Keep the meaning, tone marks and any Markdown formatting. "+
+		"Reply with the translation only.", LanguageName(sourceLang), LanguageName(targetLang))
+
+	resp, err := s.complete(ctx, UseTranslate, openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: prompt},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+		Temperature: 0.2,
+		MaxTokens:   1500,
+	})
+	if err != nil {
+		return "", err
 	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
 }
 
-func (g *GroqService) Chat(ctx context.Context, messages []openai.ChatCompletionMessage) (string, error) {
-	resp, err := g.client.CreateChatCompletion(ctx, openai.ChatCompletionRequest{
-		Model:       g.model,
-		Messages:    messages,
-		Temperature: 0.7,
-		MaxTokens:   1000,
+// ChatStream is Chat with streaming: onDelta receives each content fragment
+// as it arrives. The text received so far is returned even on error, so
+// callers can keep a partial answer. An error from onDelta stops the stream.
+// Providers are only failed over before the first fragment arrives, and a
+// provider's timeout covers its whole stream. As in complete, personal data
+// is redacted; fragments are held back while a placeholder is incomplete.
+func (s *LLMService) ChatStream(ctx context.Context, messages []openai.ChatCompletionMessage, onDelta func(string) error) (string, error) {
+	var (
+		content strings.Builder
+		lateErr error
+		usage   *openai.Usage
+	)
+	redaction := newRedactionFor(ctx)
+	messages = redactMessages(redaction, messages)
+	redaction.Log("llm")
+	restorer := &streamRestorer{r: redaction}
+
+	err := s.failover(ctx, UseChat, func(ctx context.Context, p LLMProvider, model string) error {
+		stream, err := p.CreateChatCompletionStream(ctx, openai.ChatCompletionRequest{
+			Model:       model,
+			Messages:    messages,
+			Temperature: 0.7,
+			MaxTokens:   1000,
+			Stream:      true,
+			// The usage arrives in a final chunk without choices
+			StreamOptions: &openai.StreamOptions{IncludeUsage: true},
+		})
+		if err != nil {
+			return err
+		}
+		defer stream.Close()
+		defer func() {
+			if usage != nil {
+				addUsage(ctx, model, usage.PromptTokens, usage.CompletionTokens, false)
+			} else if content.Len() > 0 {
+				addUsage(ctx, model, PromptTokens(messages), EstimateTokens(content.String()), true)
+			}
+		}()
+
+		for {
+			resp, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				if rest := restorer.Flush(); rest != "" {
+					if err := onDelta(rest); err != nil {
+						lateErr = err
+					}
+				}
+				return nil
+			}
+			if err == nil && resp.Usage != nil {
+				usage = resp.Usage
+			}
+			if err != nil {
+				if content.Len() == 0 {
+					return fmt.Errorf("stream error: %w", err)
+				}
+				lateErr = fmt.Errorf("LLM stream error: %w", err)
+				return nil
+			}
+			if len(resp.Choices) == 0 || resp.Choices[0].Delta.Content == "" {
+				continue
+			}
+			delta := resp.Choices[0].Delta.Content
+			content.WriteString(delta)
+			if delta = restorer.Write(delta); delta == "" {
+				continue
+			}
+			if err := onDelta(delta); err != nil {
+				lateErr = err
+				return nil
+			}
+		}
 	})
+	answer := redaction.Restore(content.String())
 	if err != nil {
-		return "", fmt.Errorf("groq API error: %w", err)
+		return answer, err
 	}
-	if len(resp.Choices) == 0 {
-		return "", fmt.Errorf("no response from Groq")
-	}
-	return resp.Choices[0].Message.Content, nil
+	return answer, lateErr
 }
 
-func BuildChatMessages(history []models.Message, targetLang string) []openai.ChatCompletionMessage {
+// ChatPrompt is everything BuildChatMessages assembles into a prompt.
+type ChatPrompt struct {
+	TargetLang string
+	// Summary condenses turns that no longer fit in the context window.
+	Summary string
+	History []models.Message
+	// Mode models.ChatModeTriage asks for the structured triage JSON
+	Mode string
+	// System is the rendered persona prompt; empty means the built-in
+	// medical assistant.
+	System string
+	// Passages are knowledge base excerpts the answer should cite.
+	Passages []Passage
+	// Bridge sends the English side of bridged turns, for answers that are
+	// translated from English afterwards.
+	Bridge bool
+}
+
+// BuildChatMessages assembles the prompt as system prompt, then any
+// retrieved reference passages, then the running summary of older turns,
+// then the recent turns verbatim.
+func BuildChatMessages(p ChatPrompt) []openai.ChatCompletionMessage {
+	system := p.System
+	if system == "" {
+		system, _ = RenderPrompt(BuiltinPersonas[DefaultPersona].Template, PromptVars{Language: p.TargetLang})
+	}
 	msgs := []openai.ChatCompletionMessage{
-		{
+		{Role: openai.ChatMessageRoleSystem, Content: system},
+	}
+	if p.Mode == models.ChatModeTriage {
+		msgs[0].Content += "\n\n" + triageInstructions
+	}
+	if len(p.Passages) > 0 {
+		msgs = append(msgs, openai.ChatCompletionMessage{
 			Role:    openai.ChatMessageRoleSystem,
-			Content: "You are a helpful assistant. Primary role: provide general medical information about symptoms, possible causes, and general advice. Do not provide diagnosis or treatment. Always include appropriate caution. You can also answer language-related questions (translations, grammar, usage, examples) when asked. Respond in " + targetLang + ".",
-		},
+			Content: formatPassages(p.Passages),
+		})
 	}
-	for _, m := range history {
+	if p.Summary != "" {
+		msgs = append(msgs, openai.ChatCompletionMessage{
+			Role:    openai.ChatMessageRoleSystem,
+			Content: "Summary of the earlier conversation: " + p.Summary,
+		})
+	}
+	for _, m := range p.History {
 		role := openai.ChatMessageRoleUser
 		if m.Role == "assistant" {
 			role = openai.ChatMessageRoleAssistant
 		}
+		content := m.Content
+		if p.Bridge && m.EnglishContent != "" {
+			content = m.EnglishContent
+		}
 		msgs = append(msgs, openai.ChatCompletionMessage{
 			Role:    role,
-			Content: m.Content,
+			Content: content,
 		})
 	}
 	return msgs
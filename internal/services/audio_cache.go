@@ -0,0 +1,84 @@
+package services
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+)
+
+// AudioCache is a size-bounded LRU of synthesized audio keyed by a hash of
+// everything that affects the output (provider, language, text).
+type AudioCache struct {
+	mu       sync.Mutex
+	maxBytes int
+	size     int
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+type audioCacheEntry struct {
+	key   string
+	data  []byte
+	ctype string
+}
+
+// NewAudioCache returns a cache holding at most maxBytes of audio.
+func NewAudioCache(maxBytes int) *AudioCache {
+	return &AudioCache{
+		maxBytes: maxBytes,
+		ll:       list.New(),
+		items:    map[string]*list.Element{},
+	}
+}
+
+// AudioCacheKey hashes the parts into a fixed-length cache key.
+func AudioCacheKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+// Get returns cached audio and its content type.
+func (c *AudioCache) Get(key string) ([]byte, string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.items[key]
+	if !ok {
+		return nil, "", false
+	}
+	c.ll.MoveToFront(el)
+	e := el.Value.(*audioCacheEntry)
+	return e.data, e.ctype, true
+}
+
+// Put stores audio, evicting the least recently used entries to stay under
+// the size budget. Items larger than the whole budget are not cached.
+func (c *AudioCache) Put(key string, data []byte, ctype string) {
+	if len(data) == 0 || len(data) > c.maxBytes {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		e := el.Value.(*audioCacheEntry)
+		c.size += len(data) - len(e.data)
+		e.data, e.ctype = data, ctype
+		c.ll.MoveToFront(el)
+	} else {
+		c.items[key] = c.ll.PushFront(&audioCacheEntry{key: key, data: data, ctype: ctype})
+		c.size += len(data)
+	}
+
+	for c.size > c.maxBytes {
+		el := c.ll.Back()
+		if el == nil {
+			break
+		}
+		e := el.Value.(*audioCacheEntry)
+		c.ll.Remove(el)
+		delete(c.items, e.key)
+		c.size -= len(e.data)
+	}
+}
@@ -0,0 +1,122 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+)
+
+// defaultContextBudget is the prompt token budget used when a model has no
+// entry in LLM_CONTEXT_BUDGETS. It leaves room for the 1000-token answer in
+// an 8k window.
+const defaultContextBudget = 6000
+
+// recentShare is the part of the budget kept for verbatim recent turns when
+// older turns have to be folded into the summary.
+const recentShare = 0.6
+
+// ContextBudget returns the prompt token budget for model, read from
+// LLM_CONTEXT_BUDGETS ("model=tokens,model=tokens") with LLM_CONTEXT_BUDGET
+// as the fallback.
+func ContextBudget(model string) int {
+	for _, pair := range strings.Split(os.Getenv("LLM_CONTEXT_BUDGETS"), ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || strings.TrimSpace(name) != model {
+			continue
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(value)); err == nil && n > 0 {
+			return n
+		}
+	}
+	if n, err := strconv.Atoi(strings.TrimSpace(os.Getenv("LLM_CONTEXT_BUDGET"))); err == nil && n > 0 {
+		return n
+	}
+	return defaultContextBudget
+}
+
+// EstimateTokens approximates the token count of s. Llama tokenizers average
+// about four bytes per token for English; tone-marked Yoruba/Igbo text uses
+// more bytes per letter, so counting bytes errs on the safe side.
+func EstimateTokens(s string) int {
+	if s == "" {
+		return 0
+	}
+	n := len(s)/4 + 1
+	if r := utf8.RuneCountInString(s) / 3; r > n {
+		n = r
+	}
+	return n
+}
+
+// messageTokens estimates a chat message including per-message overhead.
+func messageTokens(content string) int {
+	return EstimateTokens(content) + 4
+}
+
+// PromptTokens estimates the size of an assembled prompt.
+func PromptTokens(msgs []openai.ChatCompletionMessage) int {
+	total := 0
+	for _, m := range msgs {
+		total += messageTokens(m.Content)
+	}
+	return total
+}
+
+// RecentBudget is the share of budget kept for verbatim recent turns once
+// older turns have to be summarized.
+func RecentBudget(budget int) int {
+	return int(float64(budget) * recentShare)
+}
+
+// SplitHistory divides history into turns to fold into the summary and the
+// most recent turns that fit in budget tokens. The newest message is always
+// kept even if it alone exceeds the budget.
+func SplitHistory(history []models.Message, budget int) (older, recent []models.Message) {
+	used := 0
+	cut := len(history)
+	for i := len(history) - 1; i >= 0; i-- {
+		t := messageTokens(history[i].Content)
+		if used+t > budget && i < len(history)-1 {
+			break
+		}
+		used += t
+		cut = i
+	}
+	return history[:cut], history[cut:]
+}
+
+// Summarize folds msgs into previous, returning an updated running summary
+// of the conversation.
+func (s *LLMService) Summarize(ctx context.Context, previous string, msgs []models.Message) (string, error) {
+	var transcript strings.Builder
+	for _, m := range msgs {
+		fmt.Fprintf(&transcript, "%s (%s): %s\n", m.Role, m.Language, m.Content)
+	}
+
+	prompt := "Update the running summary of a conversation between a user and a medical information assistant. " +
+		"Keep symptoms, durations, medications, allergies, ages, advice already given and open questions. " +
+		"Write in English, at most 200 words, third person, no preamble."
+	input := "Current summary:\n" + previous + "\n\nNew turns:\n" + transcript.String()
+	if previous == "" {
+		input = "Turns:\n" + transcript.String()
+	}
+
+	resp, err := s.complete(ctx, UseSummarize, openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: prompt},
+			{Role: openai.ChatMessageRoleUser, Content: input},
+		},
+		Temperature: 0.2,
+		MaxTokens:   400,
+	})
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(resp.Choices[0].Message.Content), nil
+}
@@ -0,0 +1,393 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Emergency categories.
+const (
+	CategoryCardiac     = "cardiac"
+	CategoryRespiratory = "respiratory"
+	CategoryBleeding    = "bleeding"
+	CategoryNeuro       = "neurological"
+	CategoryMental      = "mental_health"
+	CategoryPoisoning   = "poisoning"
+	CategoryObstetric   = "obstetric"
+	CategoryOther       = "other"
+)
+
+// RedFlag is a phrase that signals an emergency in one language.
+type RedFlag struct {
+	Phrase   string `json:"phrase"`
+	Category string `json:"category"`
+	Severity string `json:"severity"`
+}
+
+// EmergencyRules are the red flags and negation cues per language code.
+type EmergencyRules struct {
+	RedFlags  map[string][]RedFlag `json:"redFlags"`
+	Negations map[string][]string  `json:"negations"`
+}
+
+// DefaultEmergencyRules covers English, Yoruba, Igbo, Hausa and Nigerian
+// Pidgin. Phrases may be written with or without tone marks.
+var DefaultEmergencyRules = EmergencyRules{
+	RedFlags: map[string][]RedFlag{
+		"en": {
+			{"chest pain", CategoryCardiac, models.SeverityCritical},
+			{"heart attack", CategoryCardiac, models.SeverityCritical},
+			{"cardiac arrest", CategoryCardiac, models.SeverityCritical},
+			{"difficulty breathing", CategoryRespiratory, models.SeverityCritical},
+			{"shortness of breath", CategoryRespiratory, models.SeverityHigh},
+			{"can't breathe", CategoryRespiratory, models.SeverityCritical},
+			{"cannot breathe", CategoryRespiratory, models.SeverityCritical},
+			{"not breathing", CategoryRespiratory, models.SeverityCritical},
+			{"choking", CategoryRespiratory, models.SeverityCritical},
+			{"severe bleeding", CategoryBleeding, models.SeverityCritical},
+			{"bleeding heavily", CategoryBleeding, models.SeverityCritical},
+			{"vomiting blood", CategoryBleeding, models.SeverityCritical},
+			{"coughing up blood", CategoryBleeding, models.SeverityHigh},
+			{"unconscious", CategoryNeuro, models.SeverityCritical},
+			{"fainting", CategoryNeuro, models.SeverityHigh},
+			{"fainted", CategoryNeuro, models.SeverityHigh},
+			{"passed out", CategoryNeuro, models.SeverityHigh},
+			{"stroke", CategoryNeuro, models.SeverityCritical},
+			{"seizure", CategoryNeuro, models.SeverityCritical},
+			{"convulsion", CategoryNeuro, models.SeverityCritical},
+			{"slurred speech", CategoryNeuro, models.SeverityCritical},
+			{"suicidal", CategoryMental, models.SeverityCritical},
+			{"kill myself", CategoryMental, models.SeverityCritical},
+			{"end my life", CategoryMental, models.SeverityCritical},
+			{"overdose", CategoryPoisoning, models.SeverityCritical},
+			{"swallowed poison", CategoryPoisoning, models.SeverityCritical},
+			{"bleeding in pregnancy", CategoryObstetric, models.SeverityCritical},
+			{"high fever", CategoryOther, models.SeverityLow},
+		},
+		"yo": {
+			{"ìrora àyà", CategoryCardiac, models.SeverityCritical},
+			{"àyà ń dùn mí", CategoryCardiac, models.SeverityCritical},
+			{"kò lè mí", CategoryRespiratory, models.SeverityCritical},
+			{"mi ò lè mí", CategoryRespiratory, models.SeverityCritical},
+			{"èémí kúkúrú", CategoryRespiratory, models.SeverityHigh},
+			{"ẹ̀jẹ̀ púpọ̀", CategoryBleeding, models.SeverityCritical},
+			{"ẹ̀jẹ̀ ń dà", CategoryBleeding, models.SeverityCritical},
+			{"dákú", CategoryNeuro, models.SeverityHigh},
+			{"gìrì", CategoryNeuro, models.SeverityCritical},
+			{"àrùn ẹ̀gbà", CategoryNeuro, models.SeverityCritical},
+			{"pa ara mi", CategoryMental, models.SeverityCritical},
+			{"májèlé", CategoryPoisoning, models.SeverityCritical},
+			{"ibà gíga", CategoryOther, models.SeverityLow},
+		},
+		"ig": {
+			{"mgbu obi", CategoryCardiac, models.SeverityCritical},
+			{"obi na-egbu m mgbu", CategoryCardiac, models.SeverityCritical},
+			{"enweghị ike iku ume", CategoryRespiratory, models.SeverityCritical},
+			{"iku ume siri ike", CategoryRespiratory, models.SeverityHigh},
+			{"ọbara na-agba", CategoryBleeding, models.SeverityCritical},
+			{"ịda mbà", CategoryNeuro, models.SeverityHigh},
+			{"ọdịdọ", CategoryNeuro, models.SeverityCritical},
+			{"igbu onwe m", CategoryMental, models.SeverityCritical},
+			{"nsí", CategoryPoisoning, models.SeverityCritical},
+			{"ahụ ọkụ dị ukwuu", CategoryOther, models.SeverityLow},
+		},
+		"ha": {
+			{"ciwon ƙirji", CategoryCardiac, models.SeverityCritical},
+			{"wahalar numfashi", CategoryRespiratory, models.SeverityCritical},
+			{"ba zan iya numfashi ba", CategoryRespiratory, models.SeverityCritical},
+			{"zubar jini", CategoryBleeding, models.SeverityCritical},
+			{"suma", CategoryNeuro, models.SeverityHigh},
+			{"farfaɗiya", CategoryNeuro, models.SeverityCritical},
+			{"shanyewar jiki", CategoryNeuro, models.SeverityCritical},
+			{"kashe kaina", CategoryMental, models.SeverityCritical},
+			{"guba", CategoryPoisoning, models.SeverityCritical},
+			{"zazzaɓi mai zafi", CategoryOther, models.SeverityLow},
+		},
+		"pcm": {
+			{"chest dey pain me", CategoryCardiac, models.SeverityCritical},
+			{"no fit breathe", CategoryRespiratory, models.SeverityCritical},
+			{"breath dey cut", CategoryRespiratory, models.SeverityHigh},
+			{"blood dey comot", CategoryBleeding, models.SeverityCritical},
+			{"don faint", CategoryNeuro, models.SeverityHigh},
+			{"wan kill myself", CategoryMental, models.SeverityCritical},
+			{"drink poison", CategoryPoisoning, models.SeverityCritical},
+		},
+	},
+	Negations: map[string][]string{
+		"en":  {"no", "not", "don't", "doesn't", "didn't", "never", "without", "denies", "free of"},
+		"yo":  {"kò", "kì í", "kìí", "kò sí"},
+		"ig":  {"enweghị", "ọ bụghị", "adịghị"},
+		"ha":  {"ba", "babu", "bai"},
+		"pcm": {"no", "never", "no dey"},
+	},
+}
+
+// negationWindow is how many words before a red flag a negation cue may
+// appear in and still cancel it ("I don't have chest pain").
+const negationWindow = 3
+
+type redFlagRule struct {
+	RedFlag
+	lang  string
+	words []string
+}
+
+// EmergencyDetector matches red-flag phrases in any of its languages, since
+// users often mix languages within a message.
+type EmergencyDetector struct {
+	rules     []redFlagRule
+	negations map[string][][]string
+}
+
+// NewEmergencyDetector compiles rules.
+func NewEmergencyDetector(rules EmergencyRules) *EmergencyDetector {
+	d := &EmergencyDetector{negations: map[string][][]string{}}
+	for lang, flags := range rules.RedFlags {
+		for _, f := range flags {
+			words := strings.Fields(NormalizeForMatch(f.Phrase))
+			if len(words) == 0 {
+				continue
+			}
+			if f.Category == "" {
+				f.Category = CategoryOther
+			}
+			if models.SeverityRank(f.Severity) == 0 {
+				f.Severity = models.SeverityHigh
+			}
+			d.rules = append(d.rules, redFlagRule{RedFlag: f, lang: lang, words: words})
+		}
+	}
+	for lang, cues := range rules.Negations {
+		for _, cue := range cues {
+			if words := strings.Fields(NormalizeForMatch(cue)); len(words) > 0 {
+				d.negations[lang] = append(d.negations[lang], words)
+			}
+		}
+	}
+	return d
+}
+
+// LoadEmergencyRules returns the default rules merged with the JSON file in
+// EMERGENCY_RULES_FILE (same shape as EmergencyRules), if set.
+func LoadEmergencyRules() (EmergencyRules, error) {
+	rules := EmergencyRules{RedFlags: map[string][]RedFlag{}, Negations: map[string][]string{}}
+	for lang, flags := range DefaultEmergencyRules.RedFlags {
+		rules.RedFlags[lang] = append([]RedFlag(nil), flags...)
+	}
+	for lang, cues := range DefaultEmergencyRules.Negations {
+		rules.Negations[lang] = append([]string(nil), cues...)
+	}
+
+	path := strings.TrimSpace(os.Getenv("EMERGENCY_RULES_FILE"))
+	if path == "" {
+		return rules, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("read emergency rules: %w", err)
+	}
+	var extra EmergencyRules
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return rules, fmt.Errorf("parse emergency rules: %w", err)
+	}
+	for lang, flags := range extra.RedFlags {
+		rules.RedFlags[lang] = append(rules.RedFlags[lang], flags...)
+	}
+	for lang, cues := range extra.Negations {
+		rules.Negations[lang] = append(rules.Negations[lang], cues...)
+	}
+	return rules, nil
+}
+
+// hookedLetters are Hausa letters with no Unicode decomposition.
+var hookedLetters = strings.NewReplacer("ɓ", "b", "ɗ", "d", "ƙ", "k", "ƴ", "y", "'", "", "’", "")
+
+// NormalizeForMatch lowercases s, strips tone marks and other diacritics,
+// and turns punctuation and hyphens into spaces, so "Ẹ̀JẸ̀" and "eje"
+// compare equal. Apostrophes are dropped so "can't" becomes "cant".
+func NormalizeForMatch(s string) string {
+	s = hookedLetters.Replace(strings.ToLower(s))
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			// combining tone mark or dot below
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteByte(' ')
+		}
+	}
+	return b.String()
+}
+
+// clauses splits text where a negation stops applying.
+func clauses(text string) []string {
+	return strings.FieldsFunc(text, func(r rune) bool {
+		switch r {
+		case '.', '!', '?', ';', ',', '\n':
+			return true
+		}
+		return false
+	})
+}
+
+// Detect returns the red flags in text. The overall severity and category
+// are those of the most severe match.
+func (d *EmergencyDetector) Detect(text string) models.EmergencyAssessment {
+	var out models.EmergencyAssessment
+	seen := map[string]bool{}
+	for _, clause := range clauses(strings.ToLower(text)) {
+		words := strings.Fields(NormalizeForMatch(clause))
+		for _, rule := range d.rules {
+			key := rule.lang + ":" + rule.Phrase
+			if seen[key] {
+				continue
+			}
+			at := indexWords(words, rule.words, 0)
+			for at >= 0 && d.negated(rule.lang, words, at) {
+				at = indexWords(words, rule.words, at+1)
+			}
+			if at < 0 {
+				continue
+			}
+			seen[key] = true
+			out.Matches = append(out.Matches, models.RedFlagMatch{
+				Phrase:   rule.Phrase,
+				Language: rule.lang,
+				Category: rule.Category,
+				Severity: rule.Severity,
+			})
+			if models.SeverityRank(rule.Severity) > models.SeverityRank(out.Severity) {
+				out.Severity = rule.Severity
+				out.Category = rule.Category
+			}
+		}
+	}
+	if len(out.Matches) > 0 {
+		out.Source = "keywords"
+	}
+	return out
+}
+
+// negated reports whether a negation cue of lang ends within the window
+// before words[at].
+func (d *EmergencyDetector) negated(lang string, words []string, at int) bool {
+	start := at - negationWindow
+	if start < 0 {
+		start = 0
+	}
+	for _, cue := range d.negations[lang] {
+		if indexWords(words[:at], cue, start) >= 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// indexWords finds phrase as consecutive whole words in words at or after
+// from, or returns -1.
+func indexWords(words, phrase []string, from int) int {
+	for i := from; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j, w := range phrase {
+			if words[i+j] != w {
+				match = false
+				break
+			}
+		}
+		if match {
+			return i
+		}
+	}
+	return -1
+}
+
+// MergeAssessments combines keyword and classifier verdicts, keeping the
+// higher severity.
+func MergeAssessments(a, b models.EmergencyAssessment) models.EmergencyAssessment {
+	out := a
+	out.Matches = append(append([]models.RedFlagMatch(nil), a.Matches...), b.Matches...)
+	if models.SeverityRank(b.Severity) > models.SeverityRank(a.Severity) {
+		out.Severity = b.Severity
+		out.Category = b.Category
+	}
+	switch {
+	case a.Source != "" && b.Source != "":
+		out.Source = a.Source + "+" + b.Source
+	case b.Source != "":
+		out.Source = b.Source
+	}
+	return out
+}
+
+// ClassifyEmergency asks the model to triage text for emergencies. It is an
+// optional second pass that catches phrasings the keyword lists miss.
+func (s *LLMService) ClassifyEmergency(ctx context.Context, text string) (models.EmergencyAssessment, error) {
+	prompt := "You screen messages sent to a health assistant in English, Yoruba, Igbo, Hausa or Nigerian Pidgin for medical emergencies. " +
+		`Reply with JSON only: {"severity":"none|low|high|critical","category":"cardiac|respiratory|bleeding|neurological|mental_health|poisoning|obstetric|other"}. ` +
+		"critical = possibly life-threatening now; high = needs urgent care today; low = should see a clinician soon. " +
+		"Symptoms the user says they do not have do not count."
+
+	resp, err := s.complete(ctx, UseChat, openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: prompt},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+		Temperature:    0,
+		MaxTokens:      60,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return models.EmergencyAssessment{}, err
+	}
+
+	var verdict struct {
+		Severity string `json:"severity"`
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &verdict); err != nil {
+		return models.EmergencyAssessment{}, fmt.Errorf("invalid classifier output: %w", err)
+	}
+	if models.SeverityRank(verdict.Severity) == 0 {
+		return models.EmergencyAssessment{}, nil
+	}
+	if verdict.Category == "" {
+		verdict.Category = CategoryOther
+	}
+	return models.EmergencyAssessment{
+		Severity: verdict.Severity,
+		Category: verdict.Category,
+		Source:   "classifier",
+	}, nil
+}
+
+// emergencyNotices are prepended to answers at high or critical severity.
+var emergencyNotices = map[string]string{
+	"en":  "Emergency warning: Your symptoms may be serious. Please seek immediate medical attention or contact local emergency services immediately.",
+	"yo":  "Ìkìlọ̀ pàjáwìrì: Àwọn àmì àìsàn rẹ lè léwu. Jọ̀wọ́ lọ sí ilé ìwòsàn tàbí pe àwọn iṣẹ́ pàjáwìrì lẹ́sẹ̀kẹsẹ̀.",
+	"ig":  "Ịdọ aka ná ntị mberede: Ihe mgbaàmà gị nwere ike ịdị njọ. Biko gaa ụlọ ọgwụ ma ọ bụ kpọọ ndị ọrụ mberede ozugbo.",
+	"ha":  "Gargaɗin gaggawa: Alamun rashin lafiyarka na iya zama masu haɗari. Don Allah ka nemi likita ko ka kira sabis na gaggawa nan take.",
+	"pcm": "Emergency warning: Wetin you dey feel fit serious. Abeg go hospital or call emergency services sharp sharp.",
+}
+
+// EmergencyNotice returns the warning to put before an answer in lang for
+// the given severity, or "" when none is needed.
+func EmergencyNotice(severity, lang string) string {
+	if models.SeverityRank(severity) < models.SeverityRank(models.SeverityHigh) {
+		return ""
+	}
+	notice, ok := emergencyNotices[strings.ToLower(strings.SplitN(lang, "-", 2)[0])]
+	if !ok {
+		notice = emergencyNotices["en"]
+	}
+	return notice + "\n\n"
+}
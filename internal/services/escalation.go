@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+var escalationClient = &http.Client{Timeout: 10 * time.Second}
+
+// EscalationConfigured reports whether EMERGENCY_WEBHOOK_URL is set.
+func EscalationConfigured() bool {
+	return strings.TrimSpace(os.Getenv("EMERGENCY_WEBHOOK_URL")) != ""
+}
+
+// EscalationMinSeverity is the lowest severity sent to the webhook
+// (EMERGENCY_WEBHOOK_MIN_SEVERITY, default high).
+func EscalationMinSeverity() string {
+	s := strings.ToLower(strings.TrimSpace(os.Getenv("EMERGENCY_WEBHOOK_MIN_SEVERITY")))
+	if models.SeverityRank(s) == 0 {
+		return models.SeverityHigh
+	}
+	return s
+}
+
+// Escalate posts event as JSON to EMERGENCY_WEBHOOK_URL. When
+// EMERGENCY_WEBHOOK_SECRET is set the body is signed with HMAC-SHA256 in
+// the X-Signature header ("sha256=<hex>"). Network errors and 5xx
+// responses are retried twice.
+func Escalate(ctx context.Context, event models.EmergencyEvent) error {
+	url := strings.TrimSpace(os.Getenv("EMERGENCY_WEBHOOK_URL"))
+	body, err := json.Marshal(map[string]any{"type": "emergency", "event": event})
+	if err != nil {
+		return fmt.Errorf("marshal webhook body: %w", err)
+	}
+
+	signature := ""
+	if secret := os.Getenv("EMERGENCY_WEBHOOK_SECRET"); secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		signature = "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 3; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return fmt.Errorf("build webhook request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if signature != "" {
+			req.Header.Set("X-Signature", signature)
+		}
+
+		resp, err := escalationClient.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("call webhook: %w", err)
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("webhook returned %d", resp.StatusCode)
+			continue
+		}
+		if resp.StatusCode >= 300 {
+			return fmt.Errorf("webhook returned %d", resp.StatusCode)
+		}
+		return nil
+	}
+	return lastErr
+}
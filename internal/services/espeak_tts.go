@@ -4,10 +4,18 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
 )
 
 // ESpeakTTS uses eSpeak-NG for text-to-speech
 func ESpeakTTS(text, lang string) ([]byte, string, error) {
+	return ESpeakTTSWithProsody(text, lang, Prosody{})
+}
+
+// ESpeakTTSWithProsody is ESpeakTTS with SSML <prosody> values mapped onto
+// espeak-ng's speed, pitch and amplitude flags.
+func ESpeakTTSWithProsody(text, lang string, p Prosody) ([]byte, string, error) {
 	// Map language codes to eSpeak voices
 	voice := map[string]string{
 		"yo-NG": "yoruba",
@@ -19,9 +27,9 @@ func ESpeakTTS(text, lang string) ([]byte, string, error) {
 	}
 
 	cmd := exec.Command("espeak-ng",
-		"-s", "160", // Speed (words per minute)
-		"-p", "50", // Pitch adjustment (0-99)
-		"-a", "100", // Amplitude (volume)
+		"-s", strconv.Itoa(espeakScale(p.Rate, 160, 80, 450)), // Speed (words per minute)
+		"-p", strconv.Itoa(espeakScale(p.Pitch, 50, 0, 99)), // Pitch adjustment (0-99)
+		"-a", strconv.Itoa(espeakScale(p.Volume, 100, 0, 200)), // Amplitude (volume)
 		"-v", voice,
 		"--stdout",
 		text,
@@ -38,3 +46,40 @@ func ESpeakTTS(text, lang string) ([]byte, string, error) {
 
 	return out.Bytes(), "audio/wav", nil
 }
+
+// espeakScale converts an SSML prosody value (keyword, percentage or
+// relative +n%/-n%) into an espeak-ng flag value around base.
+func espeakScale(v string, base, lo, hi int) int {
+	v = strings.TrimSpace(strings.ToLower(v))
+	factor := 1.0
+	switch v {
+	case "", "default", "medium":
+	case "x-slow", "x-low", "silent", "x-soft":
+		factor = 0.5
+	case "slow", "low", "soft":
+		factor = 0.75
+	case "fast", "high", "loud":
+		factor = 1.25
+	case "x-fast", "x-high", "x-loud":
+		factor = 1.5
+	default:
+		if strings.HasSuffix(v, "%") {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+			if err == nil {
+				if strings.HasPrefix(v, "+") || strings.HasPrefix(v, "-") {
+					factor = 1 + n/100
+				} else {
+					factor = n / 100
+				}
+			}
+		}
+	}
+	out := int(float64(base) * factor)
+	if out < lo {
+		out = lo
+	}
+	if out > hi {
+		out = hi
+	}
+	return out
+}
@@ -0,0 +1,248 @@
+package services
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+	"golang.org/x/text/unicode/norm"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+// The PDF font must cover Yoruba and Igbo tone marks and dots below and the
+// Hausa hooked letters, which the standard PDF fonts lack.
+var (
+	//go:embed fonts/DejaVuSansCondensed.ttf
+	exportFontRegular []byte
+	//go:embed fonts/DejaVuSansCondensed-Bold.ttf
+	exportFontBold []byte
+)
+
+// exportDisclaimers head every export.
+var exportDisclaimers = map[string]string{
+	"en":  "This conversation is with an AI health assistant. It is for information only and is not a diagnosis or a substitute for advice from a qualified health professional. In an emergency, contact local emergency services.",
+	"yo":  "Ìjíròrò yìí wáyé pẹ̀lú olùrànlọ́wọ́ ìlera AI. Fún ìmọ̀ nìkan ni, kì í ṣe àyẹ̀wò àìsàn tàbí ìdípò ìmọ̀ràn òṣìṣẹ́ ìlera tó mọṣẹ́. Ní ìgbà pàjáwìrì, pe àwọn iṣẹ́ pàjáwìrì.",
+	"ig":  "Mkparịta ụka a bụ ya na onye enyemaka ahụike AI. Ọ bụ naanị maka ozi, ọ bụghị nchọpụta ọrịa ma ọ bụ ihe nnọchi maka ndụmọdụ onye ọkachamara ahụike. N'oge mberede, kpọọ ndị ọrụ mberede.",
+	"ha":  "Wannan tattaunawa ce da mataimakin lafiya na AI. Don bayani kawai ne, ba gano cuta ba ne, kuma ba ta maye gurbin shawarar ƙwararren ma'aikacin lafiya ba. Idan akwai gaggawa, ka kira sabis na gaggawa.",
+	"pcm": "Dis conversation na with AI health assistant. Na for information only, e no be diagnosis and e no fit replace advice from qualified health worker. If na emergency, call emergency services quick quick.",
+}
+
+// ExportDisclaimer returns the medical disclaimer in lang, followed by the
+// English one so a clinician can always read it.
+func ExportDisclaimer(lang string) string {
+	code := baseLangCode(lang)
+	d, ok := exportDisclaimers[code]
+	if !ok || code == "en" {
+		return exportDisclaimers["en"]
+	}
+	return d + "\n\n" + exportDisclaimers["en"]
+}
+
+// NewConversationExport prepares the messages of a conversation's active
+// branch for export, with the disclaimer in lang.
+func NewConversationExport(conversation *models.Conversation, path []models.Message, lang string) models.ConversationExport {
+	exp := models.ConversationExport{
+		ID:         conversation.ID,
+		Title:      conversation.Title,
+		Messages:   make([]models.ExportedMessage, len(path)),
+		Disclaimer: ExportDisclaimer(lang),
+		ExportedAt: time.Now(),
+	}
+	for i, m := range path {
+		exp.Messages[i] = models.ExportedMessage{
+			Role:              m.Role,
+			Content:           m.Content,
+			Language:          m.Language,
+			LanguageName:      LanguageName(m.Language),
+			Partial:           m.Partial,
+			Severity:          m.Severity,
+			EmergencyCategory: m.EmergencyCategory,
+			Triage:            m.Triage,
+			Citations:         m.Citations,
+			CreatedAt:         m.CreatedAt,
+		}
+	}
+	return exp
+}
+
+// RedactExport replaces personal data in exp's title and messages with
+// placeholders, for views shared outside the owner's account. The default
+// rules apply even when PII_REDACTION is off. names, such as the owner's
+// account name, are redacted too, and so are their parts, since answers
+// often greet the user by first name. Triage details are dropped; the
+// message content already renders them.
+func RedactExport(exp models.ConversationExport, names ...string) models.ConversationExport {
+	texts := []string{exp.Title}
+	for _, m := range exp.Messages {
+		texts = append(texts, m.Content)
+	}
+	r := sharingPIIRedactor().NewRedaction()
+	for _, name := range names {
+		r.learn("name_list", strings.TrimSpace(name))
+	}
+	texts = r.RedactAll(texts)
+
+	exp.Title = texts[0]
+	messages := make([]models.ExportedMessage, len(exp.Messages))
+	for i, m := range exp.Messages {
+		m.Content = texts[i+1]
+		m.Triage = nil
+		messages[i] = m
+	}
+	exp.Messages = messages
+	return exp
+}
+
+const exportTimeFormat = "2 Jan 2006 15:04 MST"
+
+func exportRole(role string) string {
+	if role == "assistant" {
+		return "Assistant"
+	}
+	return "User"
+}
+
+// exportFlag describes a message's emergency flag, or "" when it has none.
+func exportFlag(m models.ExportedMessage) string {
+	if m.Severity == "" || m.Severity == models.SeverityNone {
+		return ""
+	}
+	if m.EmergencyCategory != "" {
+		return fmt.Sprintf("Emergency flagged: %s (%s)", m.Severity, strings.ReplaceAll(m.EmergencyCategory, "_", " "))
+	}
+	return "Emergency flagged: " + m.Severity
+}
+
+func exportSource(c models.Citation) string {
+	s := c.Title
+	if c.Heading != "" {
+		s += " — " + c.Heading
+	}
+	if c.Source != "" {
+		s += " (" + c.Source + ")"
+	}
+	return s
+}
+
+// ExportMarkdown renders exp as Markdown with times in loc.
+func ExportMarkdown(exp models.ConversationExport, loc *time.Location) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", exp.Title)
+	fmt.Fprintf(&b, "Exported %s · %d messages\n\n", exp.ExportedAt.In(loc).Format(exportTimeFormat), len(exp.Messages))
+	for i, para := range strings.Split(exp.Disclaimer, "\n\n") {
+		if i > 0 {
+			b.WriteString(">\n")
+		}
+		fmt.Fprintf(&b, "> **Disclaimer:** %s\n", para)
+	}
+	b.WriteString("\n---\n")
+
+	for _, m := range exp.Messages {
+		fmt.Fprintf(&b, "\n### %s · %s · %s\n\n", exportRole(m.Role), m.LanguageName, m.CreatedAt.In(loc).Format(exportTimeFormat))
+		b.WriteString(strings.TrimSpace(m.Content))
+		b.WriteString("\n")
+		if m.Partial {
+			b.WriteString("\n_(answer interrupted)_\n")
+		}
+		if flag := exportFlag(m); flag != "" {
+			fmt.Fprintf(&b, "\n> ⚠ %s\n", flag)
+		}
+		if len(m.Citations) > 0 {
+			b.WriteString("\nSources:\n")
+			for _, c := range m.Citations {
+				fmt.Fprintf(&b, "%d. %s\n", c.Number, exportSource(c))
+			}
+		}
+	}
+	return []byte(b.String())
+}
+
+var (
+	mdHeading  = regexp.MustCompile(`(?m)^#{1,6}\s+`)
+	mdBullet   = regexp.MustCompile(`(?m)^(\s*)[-*+]\s+`)
+	mdEmphasis = strings.NewReplacer("**", "", "__", "", "`", "")
+)
+
+// plainText drops the Markdown markup of answers for the PDF, keeping
+// bullets, and composes letters with their tone marks where Unicode can.
+func plainText(md string) string {
+	s := mdHeading.ReplaceAllString(md, "")
+	s = mdBullet.ReplaceAllString(s, "$1• ")
+	return norm.NFC.String(mdEmphasis.Replace(strings.TrimSpace(s)))
+}
+
+// ExportPDF renders exp as an A4 PDF with times in loc.
+func ExportPDF(exp models.ConversationExport, loc *time.Location) ([]byte, error) {
+	const font = "DejaVu"
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.AddUTF8FontFromBytes(font, "", exportFontRegular)
+	pdf.AddUTF8FontFromBytes(font, "B", exportFontBold)
+	pdf.SetTitle(exp.Title, true)
+	pdf.SetCreator("language-translator-backend", true)
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	pdf.AliasNbPages("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont(font, "", 8)
+		pdf.SetTextColor(120, 120, 120)
+		pdf.CellFormat(0, 5, fmt.Sprintf("%s · page %d of {nb}", plainText(exp.Title), pdf.PageNo()), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont(font, "B", 16)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(0, 8, plainText(exp.Title), "", "L", false)
+	pdf.SetFont(font, "", 9)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.MultiCell(0, 5, fmt.Sprintf("Exported %s · %d messages", exp.ExportedAt.In(loc).Format(exportTimeFormat), len(exp.Messages)), "", "L", false)
+	pdf.Ln(3)
+
+	pdf.SetFillColor(245, 241, 230)
+	pdf.SetTextColor(60, 60, 60)
+	pdf.MultiCell(0, 4.5, plainText(exp.Disclaimer), "1", "L", true)
+	pdf.Ln(4)
+
+	for _, m := range exp.Messages {
+		pdf.SetFont(font, "B", 10)
+		if m.Role == "assistant" {
+			pdf.SetTextColor(20, 100, 70)
+		} else {
+			pdf.SetTextColor(30, 70, 150)
+		}
+		pdf.MultiCell(0, 5.5, fmt.Sprintf("%s · %s · %s", exportRole(m.Role), m.LanguageName, m.CreatedAt.In(loc).Format(exportTimeFormat)), "", "L", false)
+
+		pdf.SetFont(font, "", 10.5)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.MultiCell(0, 5.2, plainText(m.Content), "", "L", false)
+		if m.Partial {
+			pdf.SetTextColor(120, 120, 120)
+			pdf.MultiCell(0, 5, "(answer interrupted)", "", "L", false)
+		}
+		if flag := exportFlag(m); flag != "" {
+			pdf.SetFont(font, "B", 9.5)
+			pdf.SetTextColor(180, 30, 30)
+			pdf.MultiCell(0, 5, "⚠ "+flag, "", "L", false)
+		}
+		if len(m.Citations) > 0 {
+			pdf.SetFont(font, "", 8.5)
+			pdf.SetTextColor(90, 90, 90)
+			pdf.MultiCell(0, 4.5, "Sources:", "", "L", false)
+			for _, c := range m.Citations {
+				pdf.MultiCell(0, 4.5, fmt.Sprintf("%d. %s", c.Number, plainText(exportSource(c))), "", "L", false)
+			}
+		}
+		pdf.Ln(3.5)
+	}
+
+	var buf bytes.Buffer
+	if err := pdf.Output(&buf); err != nil {
+		return nil, fmt.Errorf("render PDF: %w", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,35 @@
+package services
+
+import (
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+func TestRedactExportHidesAccountName(t *testing.T) {
+	conversation := &models.Conversation{ID: primitive.NewObjectID(), Title: "Fever for Chinedu Okafor"}
+	path := []models.Message{
+		{Role: "user", Content: "I have had a fever since Monday", Language: "en"},
+		{Role: "assistant", Content: "Hello Chinedu, I'm sorry you are unwell. Rest, Chinedu Okafor, and drink water.", Language: "en"},
+		{Role: "user", Content: "Thanks. Call me on 0803 123 4567 if needed", Language: "en"},
+	}
+
+	exp := RedactExport(NewConversationExport(conversation, path, "en"), "Chinedu Okafor")
+
+	texts := []string{exp.Title}
+	for _, m := range exp.Messages {
+		texts = append(texts, m.Content)
+	}
+	all := strings.Join(texts, "\n")
+	for _, secret := range []string{"Chinedu", "Okafor", "0803 123 4567"} {
+		if strings.Contains(all, secret) {
+			t.Errorf("shared export contains %q:\n%s", secret, all)
+		}
+	}
+	if !strings.Contains(exp.Messages[1].Content, "I'm sorry you are unwell") {
+		t.Errorf("answer over-redacted: %q", exp.Messages[1].Content)
+	}
+}
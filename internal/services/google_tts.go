@@ -1,48 +1,236 @@
 package services
 
-// import (
-// 	"context"
-// 	"fmt"
-// 	"os"
-
-// 	texttospeech "cloud.google.com/go/texttospeech/apiv1"
-// 	"cloud.google.com/go/texttospeech/apiv1/texttospeechpb"
-// )
-
-// // GoogleCloudTTS calls Google Cloud Text-to-Speech API
-// func GoogleCloudTTS(text, lang string) ([]byte, string, error) {
-// 	ctx := context.Background()
-
-// 	// Initialize client with credentials from environment
-// 	client, err := texttospeech.NewClient(ctx)
-// 	if err != nil {
-// 		return nil, "", fmt.Errorf("failed to create client: %w", err)
-// 	}
-// 	defer client.Close()
-
-// 	// Configure voice for Nigerian languages
-// 	voice := &texttospeechpb.VoiceSelectionParams{
-// 		LanguageCode: lang,
-// 		SsmlGender:   texttospeechpb.SsmlVoiceGender_FEMALE,
-// 	}
-
-// 	// Configure audio output
-// 	audioConfig := &texttospeechpb.AudioConfig{
-// 		AudioEncoding: texttospeechpb.AudioEncoding_MP3,
-// 		SpeakingRate: 1.0,
-// 	}
-
-// 	// Perform text-to-speech
-// 	resp, err := client.SynthesizeSpeech(ctx, &texttospeechpb.SynthesizeSpeechRequest{
-// 		Input: &texttospeechpb.SynthesisInput{
-// 			InputSource: &texttospeechpb.SynthesisInput_Text{Text: text},
-// 		},
-// 		Voice:       voice,
-// 		AudioConfig: audioConfig,
-// 	})
-// 	if err != nil {
-// 		return nil, "", fmt.Errorf("SynthesizeSpeech: %w", err)
-// 	}
-
-// 	return resp.AudioContent, "audio/mpeg", nil
-// }
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	googleTTSDefaultBaseURL = "https://texttospeech.googleapis.com"
+	googleTTSScope          = "https://www.googleapis.com/auth/cloud-platform"
+)
+
+// GoogleTTSClient calls the Cloud Text-to-Speech REST API. BaseURL can point
+// at a local stand-in (GOOGLE_TTS_BASE_URL), in which case credentials are
+// optional.
+type GoogleTTSClient struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+
+	creds *googleServiceAccount
+
+	mu          sync.Mutex
+	token       string
+	tokenExpiry time.Time
+}
+
+type googleServiceAccount struct {
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	PrivateKeyID string `json:"private_key_id"`
+	TokenURI     string `json:"token_uri"`
+}
+
+var (
+	googleTTSOnce   sync.Once
+	googleTTSClient *GoogleTTSClient
+	googleTTSErr    error
+)
+
+// GoogleTTSConfigured reports whether any Google TTS setting is present.
+func GoogleTTSConfigured() bool {
+	return os.Getenv("GOOGLE_APPLICATION_CREDENTIALS") != "" ||
+		strings.TrimSpace(os.Getenv("GOOGLE_TTS_API_KEY")) != "" ||
+		strings.TrimSpace(os.Getenv("GOOGLE_TTS_BASE_URL")) != ""
+}
+
+// NewGoogleTTSClientFromEnv builds a client from GOOGLE_TTS_BASE_URL,
+// GOOGLE_TTS_API_KEY and the service account file in
+// GOOGLE_APPLICATION_CREDENTIALS.
+func NewGoogleTTSClientFromEnv() (*GoogleTTSClient, error) {
+	c := &GoogleTTSClient{
+		BaseURL:    strings.TrimRight(strings.TrimSpace(os.Getenv("GOOGLE_TTS_BASE_URL")), "/"),
+		APIKey:     strings.TrimSpace(os.Getenv("GOOGLE_TTS_API_KEY")),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = googleTTSDefaultBaseURL
+	}
+
+	if path := strings.TrimSpace(os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")); path != "" && c.APIKey == "" {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("read Google credentials: %w", err)
+		}
+		var sa googleServiceAccount
+		if err := json.Unmarshal(raw, &sa); err != nil {
+			return nil, fmt.Errorf("parse Google credentials: %w", err)
+		}
+		if sa.ClientEmail == "" || sa.PrivateKey == "" {
+			return nil, fmt.Errorf("GOOGLE_APPLICATION_CREDENTIALS is not a service account key")
+		}
+		if sa.TokenURI == "" {
+			sa.TokenURI = "https://oauth2.googleapis.com/token"
+		}
+		c.creds = &sa
+	}
+
+	if c.BaseURL == googleTTSDefaultBaseURL && c.APIKey == "" && c.creds == nil {
+		return nil, fmt.Errorf("GOOGLE_TTS_API_KEY or GOOGLE_APPLICATION_CREDENTIALS is required for Google TTS")
+	}
+	return c, nil
+}
+
+// GoogleCloudTTS calls Google Cloud Text-to-Speech API. text may be a
+// <speak> document, which is sent as SSML.
+func GoogleCloudTTS(text, lang string) ([]byte, string, error) {
+	googleTTSOnce.Do(func() { googleTTSClient, googleTTSErr = NewGoogleTTSClientFromEnv() })
+	if googleTTSErr != nil {
+		return nil, "", googleTTSErr
+	}
+	return googleTTSClient.Synthesize(text, lang)
+}
+
+// Synthesize returns MP3 audio for text in lang.
+func (c *GoogleTTSClient) Synthesize(text, lang string) ([]byte, string, error) {
+	input := map[string]string{"text": text}
+	if IsSSML(text) {
+		input = map[string]string{"ssml": text}
+	}
+
+	// Configure voice for Nigerian languages; a specific voice can be pinned
+	// per base language with GOOGLE_TTS_VOICE_YO / _IG / _HA / _EN.
+	voice := map[string]string{
+		"languageCode": lang,
+		"ssmlGender":   "FEMALE",
+	}
+	base := strings.ToUpper(strings.SplitN(lang, "-", 2)[0])
+	if name := strings.TrimSpace(os.Getenv("GOOGLE_TTS_VOICE_" + base)); name != "" {
+		voice["name"] = name
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"input": input,
+		"voice": voice,
+		"audioConfig": map[string]any{
+			"audioEncoding": "MP3",
+			"speakingRate":  1.0,
+		},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("marshal request: %w", err)
+	}
+
+	u := c.BaseURL + "/v1/text:synthesize"
+	if c.APIKey != "" {
+		u += "?key=" + url.QueryEscape(c.APIKey)
+	}
+	req, err := http.NewRequest(http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return nil, "", fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.creds != nil {
+		token, err := c.accessToken()
+		if err != nil {
+			return nil, "", err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("call Google TTS: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		preview := string(respBody)
+		if len(preview) > 500 {
+			preview = preview[:500] + "..."
+		}
+		log.Printf("Google TTS error: status=%d body=%s", resp.StatusCode, preview)
+		return nil, "", fmt.Errorf("google tts %d: %s", resp.StatusCode, preview)
+	}
+
+	var out struct {
+		AudioContent string `json:"audioContent"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, "", fmt.Errorf("invalid JSON from Google TTS: %w", err)
+	}
+	audio, err := base64.StdEncoding.DecodeString(out.AudioContent)
+	if err != nil {
+		return nil, "", fmt.Errorf("decode Google TTS audio: %w", err)
+	}
+	if len(audio) == 0 {
+		return nil, "", fmt.Errorf("google tts returned empty audio")
+	}
+	return audio, "audio/mpeg", nil
+}
+
+// accessToken exchanges a signed service-account JWT for an OAuth token,
+// reusing it until shortly before it expires.
+func (c *GoogleTTSClient) accessToken() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && time.Now().Before(c.tokenExpiry) {
+		return c.token, nil
+	}
+
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(c.creds.PrivateKey))
+	if err != nil {
+		return "", fmt.Errorf("parse Google private key: %w", err)
+	}
+	now := time.Now()
+	assertion := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   c.creds.ClientEmail,
+		"scope": googleTTSScope,
+		"aud":   c.creds.TokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	assertion.Header["kid"] = c.creds.PrivateKeyID
+	signed, err := assertion.SignedString(key)
+	if err != nil {
+		return "", fmt.Errorf("sign Google token request: %w", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", signed)
+	resp, err := c.HTTPClient.PostForm(c.creds.TokenURI, form)
+	if err != nil {
+		return "", fmt.Errorf("fetch Google token: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("google token %d: %s", resp.StatusCode, string(body))
+	}
+
+	var tok struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &tok); err != nil || tok.AccessToken == "" {
+		return "", fmt.Errorf("invalid Google token response")
+	}
+	c.token = tok.AccessToken
+	c.tokenExpiry = now.Add(time.Duration(tok.ExpiresIn)*time.Second - time.Minute)
+	return c.token, nil
+}
@@ -0,0 +1,114 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGoogleTTSClientSynthesize(t *testing.T) {
+	mp3 := []byte("ID3fake-mp3")
+
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		text    string
+		wantErr string
+	}{
+		{
+			name:   "success",
+			status: http.StatusOK,
+			body:   `{"audioContent":"` + base64.StdEncoding.EncodeToString(mp3) + `"}`,
+			text:   "Báwo ni",
+		},
+		{
+			name:   "ssml",
+			status: http.StatusOK,
+			body:   `{"audioContent":"` + base64.StdEncoding.EncodeToString(mp3) + `"}`,
+			text:   "<speak>Báwo ni</speak>",
+		},
+		{
+			name:    "non-200",
+			status:  http.StatusForbidden,
+			body:    `{"error":{"message":"API key not valid"}}`,
+			text:    "Báwo ni",
+			wantErr: "google tts 403",
+		},
+		{
+			name:    "malformed JSON",
+			status:  http.StatusOK,
+			body:    `{"audioContent":`,
+			text:    "Báwo ni",
+			wantErr: "invalid JSON",
+		},
+		{
+			name:    "malformed audio",
+			status:  http.StatusOK,
+			body:    `{"audioContent":"not base64!"}`,
+			text:    "Báwo ni",
+			wantErr: "decode Google TTS audio",
+		},
+		{
+			name:    "empty audio",
+			status:  http.StatusOK,
+			body:    `{"audioContent":""}`,
+			text:    "Báwo ni",
+			wantErr: "empty audio",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Method != http.MethodPost || r.URL.Path != "/v1/text:synthesize" {
+					t.Errorf("got %s %s, want POST /v1/text:synthesize", r.Method, r.URL.Path)
+				}
+				if got := r.URL.Query().Get("key"); got != "test-key" {
+					t.Errorf("key = %q, want test-key", got)
+				}
+				var req struct {
+					Input map[string]string `json:"input"`
+					Voice map[string]string `json:"voice"`
+				}
+				if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+					t.Errorf("decode request: %v", err)
+				}
+				field := "text"
+				if IsSSML(tt.text) {
+					field = "ssml"
+				}
+				if req.Input[field] != tt.text {
+					t.Errorf("input = %v, want %s %q", req.Input, field, tt.text)
+				}
+				if req.Voice["languageCode"] != "yo-NG" {
+					t.Errorf("languageCode = %q, want yo-NG", req.Voice["languageCode"])
+				}
+
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			client := &GoogleTTSClient{BaseURL: server.URL, APIKey: "test-key", HTTPClient: server.Client()}
+			audio, ctype, err := client.Synthesize(tt.text, "yo-NG")
+
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want it to contain %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if string(audio) != string(mp3) || ctype != "audio/mpeg" {
+				t.Errorf("got %q (%s), want %q (audio/mpeg)", audio, ctype, mp3)
+			}
+		})
+	}
+}
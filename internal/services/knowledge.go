@@ -0,0 +1,293 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// maxChunkRunes is the target size of a knowledge base passage.
+const maxChunkRunes = 1200
+
+// localEmbeddingDims is the size of the offline hashed embedding.
+const localEmbeddingDims = 512
+
+// Chunk is a passage of an ingested document.
+type Chunk struct {
+	Heading string
+	Text    string
+}
+
+// ChunkDocument splits a document into passages of about maxChunkRunes.
+// Markdown headings start a new passage and are remembered as its heading;
+// paragraphs are kept whole unless a single one is too long.
+func ChunkDocument(content, format string) []Chunk {
+	var (
+		chunks  []Chunk
+		heading string
+		current []string
+		size    int
+	)
+	flush := func() {
+		if len(current) > 0 {
+			chunks = append(chunks, Chunk{Heading: heading, Text: strings.Join(current, "\n\n")})
+		}
+		current, size = nil, 0
+	}
+
+	content = strings.ReplaceAll(content, "\r\n", "\n")
+	for _, para := range strings.Split(content, "\n\n") {
+		para = strings.TrimSpace(para)
+		if para == "" {
+			continue
+		}
+		if format != "text" && strings.HasPrefix(para, "#") {
+			// A heading may share its paragraph with the text under it
+			line, rest, _ := strings.Cut(para, "\n")
+			flush()
+			heading = strings.TrimSpace(strings.TrimLeft(line, "#"))
+			if para = strings.TrimSpace(rest); para == "" {
+				continue
+			}
+		}
+
+		for _, piece := range splitLong(para) {
+			n := len([]rune(piece))
+			if size > 0 && size+n > maxChunkRunes {
+				flush()
+			}
+			current = append(current, piece)
+			size += n
+		}
+	}
+	flush()
+	return chunks
+}
+
+// splitLong breaks a paragraph longer than maxChunkRunes at sentence ends,
+// or at spaces when a sentence is itself too long.
+func splitLong(para string) []string {
+	if len([]rune(para)) <= maxChunkRunes {
+		return []string{para}
+	}
+	var (
+		out []string
+		b   strings.Builder
+	)
+	for _, word := range strings.Fields(para) {
+		if b.Len() > 0 && len([]rune(b.String()))+1+len([]rune(word)) > maxChunkRunes {
+			out = append(out, b.String())
+			b.Reset()
+		}
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		b.WriteString(word)
+		if s := b.String(); len([]rune(s)) > maxChunkRunes/2 && strings.ContainsAny(word[len(word)-1:], ".!?") {
+			out = append(out, s)
+			b.Reset()
+		}
+	}
+	if b.Len() > 0 {
+		out = append(out, b.String())
+	}
+	return out
+}
+
+// Embedder turns text into vectors for knowledge base search. It calls an
+// OpenAI-compatible embeddings API when EMBEDDING_API_KEY is set, and
+// otherwise uses a local hashed bag-of-words embedding that needs no
+// network.
+type Embedder struct {
+	client *openai.Client
+	model  string
+}
+
+func NewEmbedder() *Embedder {
+	apiKey := strings.TrimSpace(os.Getenv("EMBEDDING_API_KEY"))
+	if apiKey == "" {
+		return &Embedder{model: fmt.Sprintf("local-hash-%d", localEmbeddingDims)}
+	}
+	cfg := openai.DefaultConfig(apiKey)
+	if baseURL := strings.TrimSpace(os.Getenv("EMBEDDING_BASE_URL")); baseURL != "" {
+		cfg.BaseURL = baseURL
+	}
+	model := strings.TrimSpace(os.Getenv("EMBEDDING_MODEL"))
+	if model == "" {
+		model = string(openai.SmallEmbedding3)
+	}
+	return &Embedder{client: openai.NewClientWithConfig(cfg), model: model}
+}
+
+// Model names the embedding model; vectors from different models are not
+// comparable.
+func (e *Embedder) Model() string {
+	return e.model
+}
+
+// MinScore is the similarity below which a passage is considered
+// unrelated, from KNOWLEDGE_MIN_SCORE or a default suited to the model.
+// Hashed embeddings only overlap on shared words, so they score lower.
+func (e *Embedder) MinScore() float64 {
+	if v, err := strconv.ParseFloat(strings.TrimSpace(os.Getenv("KNOWLEDGE_MIN_SCORE")), 64); err == nil {
+		return v
+	}
+	if e.client == nil {
+		return 0.1
+	}
+	return 0.3
+}
+
+// Embed returns one unit-length vector per text.
+func (e *Embedder) Embed(ctx context.Context, texts []string) ([][]float32, error) {
+	if e.client == nil {
+		out := make([][]float32, len(texts))
+		for i, t := range texts {
+			out[i] = hashEmbedding(t)
+		}
+		return out, nil
+	}
+
+	redaction := NewRedaction()
+	redacted := redaction.RedactAll(texts)
+	redaction.Log("embeddings")
+	resp, err := e.client.CreateEmbeddings(ctx, openai.EmbeddingRequestStrings{
+		Input: redacted,
+		Model: openai.EmbeddingModel(e.model),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("embedding API error: %w", err)
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("embedding API returned %d vectors for %d texts", len(resp.Data), len(texts))
+	}
+	out := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || d.Index >= len(out) {
+			return nil, fmt.Errorf("embedding API returned index %d out of range", d.Index)
+		}
+		out[d.Index] = normalizeVector(d.Embedding)
+	}
+	return out, nil
+}
+
+// hashEmbedding hashes words and word pairs into a fixed number of signed
+// buckets, weighting repeated terms sublinearly so long passages are not
+// dominated by a few words. Tone marks are stripped so "ibà" and "iba"
+// match.
+func hashEmbedding(text string) []float32 {
+	counts := map[string]int{}
+	var prev string
+	for _, word := range strings.Fields(NormalizeForMatch(text)) {
+		if len([]rune(word)) < 3 {
+			prev = ""
+			continue
+		}
+		counts[word]++
+		if prev != "" {
+			counts[prev+" "+word]++
+		}
+		prev = word
+	}
+
+	vec := make([]float32, localEmbeddingDims)
+	for term, n := range counts {
+		h := fnv.New32a()
+		h.Write([]byte(term))
+		sum := h.Sum32()
+		weight := float32(1 + math.Log(float64(n)))
+		if sum&(1<<31) != 0 {
+			weight = -weight
+		}
+		vec[sum%localEmbeddingDims] += weight
+	}
+	return normalizeVector(vec)
+}
+
+func normalizeVector(v []float32) []float32 {
+	var sum float64
+	for _, x := range v {
+		sum += float64(x) * float64(x)
+	}
+	if sum == 0 {
+		return v
+	}
+	norm := float32(math.Sqrt(sum))
+	for i := range v {
+		v[i] /= norm
+	}
+	return v
+}
+
+// CosineSimilarity compares two vectors of the same model; 0 when their
+// lengths differ or either is empty.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+// Scored is the position of a candidate vector and its similarity.
+type Scored struct {
+	Index int
+	Score float64
+}
+
+// NearestVectors ranks candidates by cosine similarity to query and keeps
+// the best k scoring at least minScore.
+func NearestVectors(query []float32, candidates [][]float32, k int, minScore float64) []Scored {
+	var hits []Scored
+	for i, c := range candidates {
+		if score := CosineSimilarity(query, c); score >= minScore {
+			hits = append(hits, Scored{Index: i, Score: score})
+		}
+	}
+	sort.Slice(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+	return hits
+}
+
+// Passage is retrieved reference material for a prompt, numbered for
+// citation.
+type Passage struct {
+	Number  int
+	Title   string
+	Heading string
+	Text    string
+}
+
+// passageInstructions introduces retrieved passages in the prompt.
+const passageInstructions = `Reference material from vetted health sources follows. Base your answer on it where it is relevant and cite the passages you use with their numbers, like [1]. Do not cite passages you did not use. If the material does not cover the question, say so and answer carefully from general knowledge.`
+
+// formatPassages renders passages as a numbered list for the system prompt.
+func formatPassages(passages []Passage) string {
+	var b strings.Builder
+	b.WriteString(passageInstructions)
+	for _, p := range passages {
+		fmt.Fprintf(&b, "\n\n[%d] %s", p.Number, p.Title)
+		if p.Heading != "" {
+			fmt.Fprintf(&b, " — %s", p.Heading)
+		}
+		b.WriteString("\n" + p.Text)
+	}
+	return b.String()
+}
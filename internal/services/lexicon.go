@@ -0,0 +1,144 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+// Lexicon rewrites text with per-language pronunciation entries before it
+// reaches a TTS provider.
+type Lexicon struct {
+	entries []models.LexiconEntry
+	re      *regexp.Regexp
+}
+
+// NewLexicon compiles entries into a single matcher. Longer graphemes win
+// over shorter ones so "paracetamol syrup" beats "paracetamol".
+func NewLexicon(entries []models.LexiconEntry) (*Lexicon, error) {
+	if len(entries) == 0 {
+		return &Lexicon{}, nil
+	}
+
+	sorted := append([]models.LexiconEntry(nil), entries...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return utf8.RuneCountInString(sorted[i].Grapheme) > utf8.RuneCountInString(sorted[j].Grapheme)
+	})
+
+	kept := sorted[:0]
+	alts := make([]string, 0, len(sorted))
+	for _, e := range sorted {
+		g := regexp.QuoteMeta(strings.TrimSpace(e.Grapheme))
+		if g == "" {
+			continue
+		}
+		kept = append(kept, e)
+		if e.CaseSensitive {
+			alts = append(alts, g)
+		} else {
+			alts = append(alts, "(?i:"+g+")")
+		}
+	}
+	if len(alts) == 0 {
+		// An empty alternation would match everywhere
+		return &Lexicon{}, nil
+	}
+	re, err := regexp.Compile(strings.Join(alts, "|"))
+	if err != nil {
+		return nil, fmt.Errorf("compile lexicon: %w", err)
+	}
+	return &Lexicon{entries: kept, re: re}, nil
+}
+
+// Rewrite returns a copy of doc with lexicon entries applied. When
+// usePhonemes is set, entries with IPA become <phoneme> segments for
+// providers with full SSML support; otherwise the respelling is used.
+func (l *Lexicon) Rewrite(doc *SpeechDocument, usePhonemes bool) *SpeechDocument {
+	if l == nil || l.re == nil {
+		return doc
+	}
+
+	out := &SpeechDocument{IsSSML: doc.IsSSML}
+	for _, seg := range doc.Segments {
+		if seg.Text == "" || seg.Phoneme != "" {
+			out.Segments = append(out.Segments, seg)
+			continue
+		}
+
+		var text strings.Builder
+		flush := func() {
+			if text.Len() > 0 {
+				out.Segments = append(out.Segments, SpeechSegment{Text: strings.TrimSpace(text.String()), Prosody: seg.Prosody})
+				text.Reset()
+			}
+		}
+
+		last := 0
+		for _, m := range l.re.FindAllStringIndex(seg.Text, -1) {
+			if !isWordBoundary(seg.Text, m[0], m[1]) {
+				continue
+			}
+			entry, ok := l.lookup(seg.Text[m[0]:m[1]])
+			if !ok {
+				continue
+			}
+			text.WriteString(seg.Text[last:m[0]])
+			last = m[1]
+
+			spoken := entry.Respelling
+			if spoken == "" {
+				spoken = seg.Text[m[0]:m[1]]
+			}
+			if usePhonemes && entry.IPA != "" {
+				flush()
+				out.Segments = append(out.Segments, SpeechSegment{Text: spoken, Phoneme: entry.IPA, Prosody: seg.Prosody})
+				out.IsSSML = true
+				continue
+			}
+			text.WriteString(spoken)
+		}
+		text.WriteString(seg.Text[last:])
+		flush()
+	}
+	return out
+}
+
+func (l *Lexicon) lookup(match string) (models.LexiconEntry, bool) {
+	for _, e := range l.entries {
+		g := strings.TrimSpace(e.Grapheme)
+		if e.CaseSensitive && g == match {
+			return e, true
+		}
+		if !e.CaseSensitive && strings.EqualFold(g, match) {
+			return e, true
+		}
+	}
+	return models.LexiconEntry{}, false
+}
+
+// isWordBoundary reports whether s[start:end] is not glued to surrounding
+// letters or digits. regexp's \b is ASCII-only, which breaks on tone marks.
+func isWordBoundary(s string, start, end int) bool {
+	if start > 0 {
+		r, _ := utf8.DecodeLastRuneInString(s[:start])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	if end < len(s) {
+		r, _ := utf8.DecodeRuneInString(s[end:])
+		if isWordRune(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.Is(unicode.Mn, r)
+}
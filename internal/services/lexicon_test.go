@@ -0,0 +1,50 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+func TestLexiconRewrite(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []models.LexiconEntry
+		text    string
+		want    string
+	}{
+		{
+			name:    "respelling",
+			entries: []models.LexiconEntry{{Grapheme: "paracetamol", Respelling: "para-SEE-ta-mol"}},
+			text:    "Take Paracetamol twice",
+			want:    "Take para-SEE-ta-mol twice",
+		},
+		{
+			name:    "blank grapheme only",
+			entries: []models.LexiconEntry{{Grapheme: "  ", Respelling: "oops"}},
+			text:    "Take paracetamol twice",
+			want:    "Take paracetamol twice",
+		},
+		{
+			name: "blank grapheme among others",
+			entries: []models.LexiconEntry{
+				{Grapheme: "", Respelling: "oops"},
+				{Grapheme: "ọmọ", Respelling: "aw-maw", CaseSensitive: true},
+			},
+			text: "Ọmọ mi, ọmọ",
+			want: "Ọmọ mi, aw-maw",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lexicon, err := NewLexicon(tt.entries)
+			if err != nil {
+				t.Fatalf("NewLexicon: %v", err)
+			}
+			doc := lexicon.Rewrite(&SpeechDocument{Segments: []SpeechSegment{{Text: tt.text}}}, false)
+			if got := doc.PlainText(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
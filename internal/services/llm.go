@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// LLM use cases; each provider can serve them with a different model.
+const (
+	UseChat       = "chat"
+	UseTranslate  = "translate"
+	UseSummarize  = "summarize"
+	UseTranscribe = "transcribe"
+)
+
+// defaultLLMTimeout bounds one request to one provider.
+const defaultLLMTimeout = 60 * time.Second
+
+// LLMProvider is one model endpoint the LLM service can fail over between.
+type LLMProvider interface {
+	Name() string
+	// Model is the model for a use case; empty means the provider does not
+	// serve it.
+	Model(useCase string) string
+	Timeout() time.Duration
+	CreateChatCompletion(ctx context.Context, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error)
+	CreateChatCompletionStream(ctx context.Context, req openai.ChatCompletionRequest) (*openai.ChatCompletionStream, error)
+	CreateTranscription(ctx context.Context, req openai.AudioRequest) (openai.AudioResponse, error)
+}
+
+// ProviderConfig describes an OpenAI-compatible endpoint.
+type ProviderConfig struct {
+	Name    string
+	BaseURL string
+	APIKey  string
+	Timeout time.Duration
+	Models  map[string]string // use case -> model
+}
+
+// knownProviders are the base URLs of endpoints that can be named without
+// configuring one.
+var knownProviders = map[string]string{
+	"groq":     "https://api.groq.com/openai/v1",
+	"openai":   "https://api.openai.com/v1",
+	"ollama":   "http://localhost:11434/v1",
+	"llamacpp": "http://localhost:8080/v1",
+}
+
+// hostedProviders need an API key, read from the named variable when
+// LLM_<NAME>_API_KEY is not set.
+var hostedProviders = map[string]string{"groq": "GROQ_API_KEY", "openai": "OPENAI_API_KEY"}
+
+type openAIProvider struct {
+	name    string
+	timeout time.Duration
+	models  map[string]string
+	*openai.Client
+}
+
+// NewOpenAIProvider talks to any OpenAI-compatible API, such as Groq,
+// OpenAI, Ollama or a llama.cpp server.
+func NewOpenAIProvider(cfg ProviderConfig) LLMProvider {
+	clientCfg := openai.DefaultConfig(cfg.APIKey)
+	clientCfg.BaseURL = cfg.BaseURL
+	return &openAIProvider{
+		name:    cfg.Name,
+		timeout: cfg.Timeout,
+		models:  cfg.Models,
+		Client:  openai.NewClientWithConfig(clientCfg),
+	}
+}
+
+func (p *openAIProvider) Name() string                { return p.name }
+func (p *openAIProvider) Model(useCase string) string { return p.models[useCase] }
+func (p *openAIProvider) Timeout() time.Duration      { return p.timeout }
+
+// LoadLLMConfig reads the provider list from the environment. LLM_PROVIDERS
+// names the providers in failover order; each is configured with
+// LLM_<NAME>_BASE_URL, _API_KEY, _TIMEOUT (e.g. "30s"), _MODEL (the default
+// for every use case) and _CHAT_MODEL, _TRANSLATE_MODEL, _SUMMARIZE_MODEL,
+// _STT_MODEL overrides. Without LLM_PROVIDERS the Groq settings
+// (GROQ_API_KEY, GROQ_MODEL, GROQ_STT_MODEL) are used when present. No
+// providers at all is not an error; chat is then unavailable.
+func LoadLLMConfig() ([]ProviderConfig, error) {
+	names := strings.TrimSpace(os.Getenv("LLM_PROVIDERS"))
+	if names == "" {
+		key := strings.TrimSpace(os.Getenv("GROQ_API_KEY"))
+		if key == "" {
+			return nil, nil
+		}
+		chat := envOr("GROQ_MODEL", "llama-3.1-70b-versatile")
+		return []ProviderConfig{{
+			Name:    "groq",
+			BaseURL: knownProviders["groq"],
+			APIKey:  key,
+			Timeout: defaultLLMTimeout,
+			Models: map[string]string{
+				UseChat:       chat,
+				UseTranslate:  chat,
+				UseSummarize:  chat,
+				UseTranscribe: envOr("GROQ_STT_MODEL", "whisper-large-v3"),
+			},
+		}}, nil
+	}
+
+	var (
+		configs []ProviderConfig
+		errs    []error
+		seen    = map[string]bool{}
+	)
+	for _, name := range strings.Split(names, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if seen[name] {
+			errs = append(errs, fmt.Errorf("provider %q is listed twice", name))
+			continue
+		}
+		seen[name] = true
+
+		cfg, err := loadProviderConfig(name)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		configs = append(configs, cfg)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("LLM_PROVIDERS lists no providers")
+	}
+	return configs, nil
+}
+
+func loadProviderConfig(name string) (ProviderConfig, error) {
+	prefix := "LLM_" + strings.ToUpper(strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)) + "_"
+
+	cfg := ProviderConfig{
+		Name:    name,
+		BaseURL: envOr(prefix+"BASE_URL", knownProviders[name]),
+		APIKey:  envOr(prefix+"API_KEY", strings.TrimSpace(os.Getenv(hostedProviders[name]))),
+		Timeout: defaultLLMTimeout,
+		Models:  map[string]string{},
+	}
+
+	if cfg.BaseURL == "" {
+		return cfg, fmt.Errorf("provider %q: %sBASE_URL is not set", name, prefix)
+	}
+	u, err := url.Parse(cfg.BaseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return cfg, fmt.Errorf("provider %q: %sBASE_URL %q is not an http(s) URL", name, prefix, cfg.BaseURL)
+	}
+	if _, hosted := hostedProviders[name]; hosted && cfg.APIKey == "" {
+		return cfg, fmt.Errorf("provider %q: %sAPI_KEY is not set", name, prefix)
+	}
+	if v := strings.TrimSpace(os.Getenv(prefix + "TIMEOUT")); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("provider %q: %sTIMEOUT %q is not a positive duration", name, prefix, v)
+		}
+		cfg.Timeout = d
+	}
+
+	model := strings.TrimSpace(os.Getenv(prefix + "MODEL"))
+	for useCase, key := range map[string]string{
+		UseChat:      "CHAT_MODEL",
+		UseTranslate: "TRANSLATE_MODEL",
+		UseSummarize: "SUMMARIZE_MODEL",
+	} {
+		if m := envOr(prefix+key, model); m != "" {
+			cfg.Models[useCase] = m
+		}
+	}
+	if cfg.Models[UseChat] == "" {
+		return cfg, fmt.Errorf("provider %q: set %sMODEL or %sCHAT_MODEL", name, prefix, prefix)
+	}
+	// Transcription is opt-in: chat models cannot transcribe
+	if m := strings.TrimSpace(os.Getenv(prefix + "STT_MODEL")); m != "" {
+		cfg.Models[UseTranscribe] = m
+	}
+	return cfg, nil
+}
+
+func envOr(key, fallback string) string {
+	if v := strings.TrimSpace(os.Getenv(key)); v != "" {
+		return v
+	}
+	return fallback
+}
+
+// LLMService runs model requests against its providers in order, moving on
+// to the next when one fails or times out.
+type LLMService struct {
+	providers []LLMProvider
+}
+
+func NewLLMService(providers ...LLMProvider) *LLMService {
+	return &LLMService{providers: providers}
+}
+
+var defaultLLM *LLMService
+
+// InitLLM loads and validates the provider config and sets up the shared
+// LLM service. It is called once at startup.
+func InitLLM() error {
+	configs, err := LoadLLMConfig()
+	if err != nil {
+		return err
+	}
+	if len(configs) == 0 {
+		log.Printf("LLM: no providers configured; chat is disabled")
+		return nil
+	}
+	providers := make([]LLMProvider, len(configs))
+	names := make([]string, len(configs))
+	for i, cfg := range configs {
+		providers[i] = NewOpenAIProvider(cfg)
+		names[i] = cfg.Name
+	}
+	defaultLLM = NewLLMService(providers...)
+	log.Printf("LLM: providers %s", strings.Join(names, " -> "))
+	return nil
+}
+
+// LLM returns the shared LLM service, or nil when no provider is
+// configured.
+func LLM() *LLMService {
+	return defaultLLM
+}
+
+// Model returns the chat model of the first provider, used to look up the
+// context budget.
+func (s *LLMService) Model() string {
+	for _, p := range s.providers {
+		if m := p.Model(UseChat); m != "" {
+			return m
+		}
+	}
+	return ""
+}
+
+// Serves reports whether any provider handles useCase.
+func (s *LLMService) Serves(useCase string) bool {
+	for _, p := range s.providers {
+		if p.Model(useCase) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// failover calls try with each provider serving useCase until one succeeds.
+// It stops early when ctx itself is done.
+func (s *LLMService) failover(ctx context.Context, useCase string, try func(ctx context.Context, p LLMProvider, model string) error) error {
+	var errs []error
+	for _, p := range s.providers {
+		model := p.Model(useCase)
+		if model == "" {
+			continue
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, p.Timeout())
+		err := try(attemptCtx, p, model)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+		if ctx.Err() != nil {
+			break
+		}
+		log.Printf("LLM: %s failed for %s: %v", p.Name(), useCase, err)
+	}
+	if len(errs) == 0 {
+		return fmt.Errorf("no LLM provider serves %s", useCase)
+	}
+	return fmt.Errorf("LLM error: %w", errors.Join(errs...))
+}
+
+// complete sends one chat completion for useCase, filling in the model,
+// and counts its tokens on ctx. Personal data, including names added with
+// WithPIINames, is redacted from the request and restored in the
+// response. The response always has at least one choice.
+func (s *LLMService) complete(ctx context.Context, useCase string, req openai.ChatCompletionRequest) (openai.ChatCompletionResponse, error) {
+	redaction := newRedactionFor(ctx)
+	req.Messages = redactMessages(redaction, req.Messages)
+	redaction.Log("llm")
+
+	var resp openai.ChatCompletionResponse
+	err := s.failover(ctx, useCase, func(ctx context.Context, p LLMProvider, model string) error {
+		req.Model = model
+		r, err := p.CreateChatCompletion(ctx, req)
+		if err != nil {
+			return err
+		}
+		if len(r.Choices) == 0 {
+			return fmt.Errorf("no response from model")
+		}
+		if r.Usage.TotalTokens > 0 {
+			addUsage(ctx, model, r.Usage.PromptTokens, r.Usage.CompletionTokens, false)
+		} else {
+			addUsage(ctx, model, PromptTokens(req.Messages), EstimateTokens(r.Choices[0].Message.Content), true)
+		}
+		resp = r
+		return nil
+	})
+	if err == nil {
+		msg := &resp.Choices[0].Message
+		msg.Content = redaction.Restore(msg.Content)
+		for i := range msg.ToolCalls {
+			msg.ToolCalls[i].Function.Arguments = redaction.Restore(msg.ToolCalls[i].Function.Arguments)
+		}
+	}
+	return resp, err
+}
+
+// redactMessages returns a copy of msgs with personal data redacted from
+// their text and tool call arguments.
+func redactMessages(redaction *Redaction, msgs []openai.ChatCompletionMessage) []openai.ChatCompletionMessage {
+	var texts []string
+	for _, m := range msgs {
+		texts = append(texts, m.Content)
+		for _, call := range m.ToolCalls {
+			texts = append(texts, call.Function.Arguments)
+		}
+	}
+	texts = redaction.RedactAll(texts)
+
+	out := make([]openai.ChatCompletionMessage, len(msgs))
+	for i, m := range msgs {
+		m.Content, texts = texts[0], texts[1:]
+		if len(m.ToolCalls) > 0 {
+			calls := append([]openai.ToolCall(nil), m.ToolCalls...)
+			for j := range calls {
+				calls[j].Function.Arguments, texts = texts[0], texts[1:]
+			}
+			m.ToolCalls = calls
+		}
+		out[i] = m
+	}
+	return out
+}
@@ -0,0 +1,172 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+)
+
+// Moderation categories.
+const (
+	ModerationThreat     = "threat"
+	ModerationHarassment = "harassment"
+	ModerationHate       = "hate"
+	ModerationSexual     = "sexual"
+)
+
+// BlockedPhrase is a phrase that gets input refused.
+type BlockedPhrase struct {
+	Phrase   string `json:"phrase"`
+	Category string `json:"category"`
+}
+
+// ModerationRules are the blocked phrases per language code.
+type ModerationRules struct {
+	Blocked map[string][]BlockedPhrase `json:"blocked"`
+}
+
+// DefaultModerationRules only block unambiguous threats and abuse aimed at
+// others. Profanity, self-harm (handled as an emergency) and sexual health
+// questions are deliberately allowed in a medical chat.
+var DefaultModerationRules = ModerationRules{
+	Blocked: map[string][]BlockedPhrase{
+		"en": {
+			{"i will kill you", ModerationThreat},
+			{"i'll kill you", ModerationThreat},
+			{"i am going to kill you", ModerationThreat},
+			{"i will hurt you", ModerationThreat},
+			{"bomb the hospital", ModerationThreat},
+			{"fuck you", ModerationHarassment},
+			{"go kill yourself", ModerationHarassment},
+		},
+		"yo": {
+			{"màá pa ẹ́", ModerationThreat},
+		},
+		"ig": {
+			{"aga m egbu gị", ModerationThreat},
+		},
+		"ha": {
+			{"zan kashe ka", ModerationThreat},
+			{"zan kashe ki", ModerationThreat},
+		},
+		"pcm": {
+			{"i go kill you", ModerationThreat},
+			{"i go wound you", ModerationThreat},
+		},
+	},
+}
+
+// LoadModerationRules returns the default rules merged with the JSON file in
+// MODERATION_RULES_FILE (same shape as ModerationRules), if set.
+func LoadModerationRules() (ModerationRules, error) {
+	rules := ModerationRules{Blocked: map[string][]BlockedPhrase{}}
+	for lang, phrases := range DefaultModerationRules.Blocked {
+		rules.Blocked[lang] = append([]BlockedPhrase(nil), phrases...)
+	}
+
+	path := strings.TrimSpace(os.Getenv("MODERATION_RULES_FILE"))
+	if path == "" {
+		return rules, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("read moderation rules: %w", err)
+	}
+	var extra ModerationRules
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return rules, fmt.Errorf("parse moderation rules: %w", err)
+	}
+	for lang, phrases := range extra.Blocked {
+		rules.Blocked[lang] = append(rules.Blocked[lang], phrases...)
+	}
+	return rules, nil
+}
+
+type blockedRule struct {
+	BlockedPhrase
+	words []string
+}
+
+// Moderator matches blocked phrases in any of its languages, ignoring case
+// and tone marks.
+type Moderator struct {
+	rules []blockedRule
+}
+
+// NewModerator compiles rules.
+func NewModerator(rules ModerationRules) *Moderator {
+	m := &Moderator{}
+	for _, phrases := range rules.Blocked {
+		for _, p := range phrases {
+			words := strings.Fields(NormalizeForMatch(p.Phrase))
+			if len(words) == 0 {
+				continue
+			}
+			if p.Category == "" {
+				p.Category = ModerationHarassment
+			}
+			m.rules = append(m.rules, blockedRule{BlockedPhrase: p, words: words})
+		}
+	}
+	return m
+}
+
+// Check returns the blocked phrases in text.
+func (m *Moderator) Check(text string) models.ModerationResult {
+	var out models.ModerationResult
+	words := strings.Fields(NormalizeForMatch(text))
+	for _, rule := range m.rules {
+		if indexWords(words, rule.words, 0) < 0 {
+			continue
+		}
+		if !out.Flagged {
+			out.Flagged = true
+			out.Category = rule.Category
+			out.Source = "keywords"
+		}
+		out.Matches = append(out.Matches, rule.Phrase)
+	}
+	return out
+}
+
+// Moderate asks the model whether text is abusive. It is an optional second
+// pass that catches abuse the phrase lists miss.
+func (s *LLMService) Moderate(ctx context.Context, text string) (models.ModerationResult, error) {
+	prompt := "You moderate messages sent to a health assistant in English, Yoruba, Igbo, Hausa or Nigerian Pidgin. " +
+		`Reply with JSON only: {"flagged":true|false,"category":"threat|harassment|hate|sexual|none"}. ` +
+		"Flag only threats of violence, harassment or hate aimed at people, and sexual content involving minors or meant to harass. " +
+		"Never flag symptoms, body parts, sexual or reproductive health questions, profanity about the user's own condition, or self-harm."
+
+	resp, err := s.complete(ctx, UseChat, openai.ChatCompletionRequest{
+		Messages: []openai.ChatCompletionMessage{
+			{Role: openai.ChatMessageRoleSystem, Content: prompt},
+			{Role: openai.ChatMessageRoleUser, Content: text},
+		},
+		Temperature:    0,
+		MaxTokens:      40,
+		ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+	})
+	if err != nil {
+		return models.ModerationResult{}, err
+	}
+
+	var verdict struct {
+		Flagged  bool   `json:"flagged"`
+		Category string `json:"category"`
+	}
+	if err := json.Unmarshal([]byte(resp.Choices[0].Message.Content), &verdict); err != nil {
+		return models.ModerationResult{}, fmt.Errorf("invalid moderation output: %w", err)
+	}
+	if !verdict.Flagged {
+		return models.ModerationResult{}, nil
+	}
+	if verdict.Category == "" || verdict.Category == "none" {
+		verdict.Category = ModerationHarassment
+	}
+	return models.ModerationResult{Flagged: true, Category: verdict.Category, Source: "classifier"}, nil
+}
@@ -0,0 +1,210 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BoundingBox is a pixel rectangle in the source image.
+type BoundingBox struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// union grows b to cover o.
+func (b BoundingBox) union(o BoundingBox) BoundingBox {
+	if b.Width == 0 && b.Height == 0 {
+		return o
+	}
+	x1, y1 := min(b.X, o.X), min(b.Y, o.Y)
+	x2, y2 := max(b.X+b.Width, o.X+o.Width), max(b.Y+b.Height, o.Y+o.Height)
+	return BoundingBox{X: x1, Y: y1, Width: x2 - x1, Height: y2 - y1}
+}
+
+// OCRBlock is a block of text found in an image.
+type OCRBlock struct {
+	Text       string      `json:"text"`
+	Box        BoundingBox `json:"box"`
+	Confidence float64     `json:"confidence"` // 0-100
+}
+
+// OCRProvider is a text-recognition backend. lang is a base code (en, yo, ig, ha).
+type OCRProvider struct {
+	Name      string
+	Recognize func(ctx context.Context, image []byte, lang string) ([]OCRBlock, error)
+}
+
+var (
+	// TesseractOCR shells out to the tesseract CLI.
+	TesseractOCR = OCRProvider{Name: "Tesseract", Recognize: TesseractRecognize}
+	// RemoteOCR posts the image to OCR_REMOTE_URL.
+	RemoteOCR = OCRProvider{Name: "Remote", Recognize: RemoteRecognize}
+)
+
+// tesseractLangs maps base language codes to tesseract traineddata names.
+var tesseractLangs = map[string]string{
+	"en": "eng",
+	"yo": "yor",
+	"ig": "ibo",
+	"ha": "hau",
+	"fr": "fra",
+}
+
+// TesseractRecognize runs `tesseract stdin stdout tsv` and groups words into
+// blocks. English is always added so loanwords and drug names still read.
+func TesseractRecognize(ctx context.Context, image []byte, lang string) ([]OCRBlock, error) {
+	langs := "eng"
+	if l, ok := tesseractLangs[baseLangCode(lang)]; ok && l != "eng" {
+		langs = l + "+eng"
+	}
+
+	cmd := exec.CommandContext(ctx, "tesseract", "stdin", "stdout", "-l", langs, "tsv")
+	cmd.Stdin = bytes.NewReader(image)
+
+	var out bytes.Buffer
+	var stderr bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("tesseract failed: %s - %w", stderr.String(), err)
+	}
+
+	return parseTesseractTSV(out.Bytes())
+}
+
+// parseTesseractTSV collects level-5 (word) rows per block_num.
+func parseTesseractTSV(data []byte) ([]OCRBlock, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = '\t'
+	r.LazyQuotes = true
+	r.FieldsPerRecord = -1
+
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("parse tesseract output: %w", err)
+	}
+
+	type acc struct {
+		words   []string
+		box     BoundingBox
+		confSum float64
+		line    [2]int // par_num, line_num of the last word, to insert line breaks
+	}
+	blocks := map[int]*acc{}
+	var order []int
+
+	for i, row := range rows {
+		if i == 0 || len(row) < 12 {
+			continue // header
+		}
+		if row[0] != "5" {
+			continue
+		}
+		text := strings.TrimSpace(row[11])
+		if text == "" {
+			continue
+		}
+		num := func(j int) int { n, _ := strconv.Atoi(row[j]); return n }
+		conf, _ := strconv.ParseFloat(row[10], 64)
+		blockNum, parNum, lineNum := num(2), num(3), num(4)
+
+		a, ok := blocks[blockNum]
+		if !ok {
+			a = &acc{line: [2]int{parNum, lineNum}}
+			blocks[blockNum] = a
+			order = append(order, blockNum)
+		} else if a.line[0] != parNum || a.line[1] != lineNum {
+			a.words = append(a.words, "\n")
+			a.line = [2]int{parNum, lineNum}
+		}
+		a.words = append(a.words, text)
+		a.box = a.box.union(BoundingBox{X: num(6), Y: num(7), Width: num(8), Height: num(9)})
+		a.confSum += conf
+	}
+
+	sort.Ints(order)
+	out := make([]OCRBlock, 0, len(order))
+	for _, n := range order {
+		a := blocks[n]
+		words := 0
+		var b strings.Builder
+		for _, w := range a.words {
+			if w == "\n" {
+				b.WriteString("\n")
+				continue
+			}
+			if b.Len() > 0 && !strings.HasSuffix(b.String(), "\n") {
+				b.WriteString(" ")
+			}
+			b.WriteString(w)
+			words++
+		}
+		out = append(out, OCRBlock{
+			Text:       b.String(),
+			Box:        a.box,
+			Confidence: a.confSum / float64(words),
+		})
+	}
+	return out, nil
+}
+
+// RemoteRecognize posts the image to OCR_REMOTE_URL (with ?lang=) and
+// expects {"blocks":[{"text":"...","box":{"x":0,"y":0,"width":0,"height":0},"confidence":0}]}.
+func RemoteRecognize(ctx context.Context, image []byte, lang string) ([]OCRBlock, error) {
+	endpoint := strings.TrimSpace(os.Getenv("OCR_REMOTE_URL"))
+	if endpoint == "" {
+		return nil, fmt.Errorf("OCR_REMOTE_URL is not configured")
+	}
+	if strings.Contains(endpoint, "?") {
+		endpoint += "&lang=" + baseLangCode(lang)
+	} else {
+		endpoint += "?lang=" + baseLangCode(lang)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(image))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", http.DetectContentType(image))
+	req.Header.Set("Accept", "application/json")
+	if key := strings.TrimSpace(os.Getenv("OCR_REMOTE_API_KEY")); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+
+	client := &http.Client{Timeout: 60 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call OCR service: %w", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		preview := string(body)
+		if len(preview) > 500 {
+			preview = preview[:500] + "..."
+		}
+		return nil, fmt.Errorf("ocr %d: %s", resp.StatusCode, preview)
+	}
+
+	var out struct {
+		Blocks []OCRBlock `json:"blocks"`
+	}
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("invalid JSON from OCR service: %w", err)
+	}
+	return out.Blocks, nil
+}
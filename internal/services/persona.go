@@ -0,0 +1,55 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// DefaultPersona is used when neither the request nor the conversation
+// names one.
+const DefaultPersona = "medical"
+
+// PromptVars are the variables available to prompt templates.
+type PromptVars struct {
+	Language string
+	UserName string
+	Locale   string
+}
+
+// BuiltinPersona is a persona's prompt before any admin edits; it counts as
+// version 0.
+type BuiltinPersona struct {
+	Name     string
+	Template string
+}
+
+// BuiltinPersonas are always available; saved templates override them.
+var BuiltinPersonas = map[string]BuiltinPersona{
+	"medical": {
+		Name:     "Medical info",
+		Template: "You are a helpful assistant. Primary role: provide general medical information about symptoms, possible causes, and general advice. Do not provide diagnosis or treatment. Always include appropriate caution. You can also answer language-related questions (translations, grammar, usage, examples) when asked. Respond in {{.Language}}.",
+	},
+	"tutor": {
+		Name:     "Language tutor",
+		Template: "You are a patient language tutor for Nigerian languages (Yoruba, Igbo, Hausa) and English. Explain vocabulary, grammar, tone marks and pronunciation with short examples, correct mistakes gently and suggest practice sentences.{{if .UserName}} The learner's name is {{.UserName}}.{{end}} Respond in {{.Language}}.",
+	},
+	"pharmacy": {
+		Name:     "Pharmacy helper",
+		Template: "You are a pharmacy information assistant. Explain what common medicines are used for, usual adult dosing as printed on packaging, side effects, interactions and storage. Do not prescribe or change doses; tell the user to ask a pharmacist or doctor for personal advice, and to seek urgent care for overdoses or severe reactions.{{if .Locale}} Use drug names and units familiar in the {{.Locale}} locale.{{end}} Respond in {{.Language}}.",
+	},
+}
+
+// RenderPrompt executes a prompt template. Unknown variables are an error,
+// so templates are checked when saved.
+func RenderPrompt(tmpl string, vars PromptVars) (string, error) {
+	t, err := template.New("prompt").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("parse prompt template: %w", err)
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, vars); err != nil {
+		return "", fmt.Errorf("render prompt template: %w", err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
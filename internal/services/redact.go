@@ -0,0 +1,507 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+// PII types, used in placeholders such as [PHONE_1].
+const (
+	PIIPhone = "PHONE"
+	PIIEmail = "EMAIL"
+	PIIID    = "ID"
+	PIIName  = "NAME"
+)
+
+// PIIRule finds one kind of personal data with a regular expression. When
+// the pattern has a capture group only the group is replaced, so cues such
+// as "my name is" stay in the text.
+type PIIRule struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Pattern string `json:"pattern"`
+}
+
+// PIIRules are the patterns to redact, tried in order, and literal names
+// (e.g. staff or common patient names) matched as whole words in any case.
+type PIIRules struct {
+	Rules []PIIRule `json:"rules"`
+	Names []string  `json:"names"`
+}
+
+// nameWords is one to three words of a name after a cue. The first word
+// may be lowercase since users often don't capitalize names; trimName
+// rejects common words instead.
+const nameWords = `([\p{L}\p{M}'’-]+(?:\s+\p{Lu}[\p{L}\p{M}'’-]*){0,2})`
+
+// nameStopWords are common words that follow a name cue without being a
+// name ("my name is a secret", "my name is not important"), compared after
+// NormalizeForMatch.
+var nameStopWords = map[string]bool{}
+
+func init() {
+	for _, w := range strings.Fields(`
+		a an the not no is was are am be been it its this that these those
+		what which who how why my your his her our their me him them us
+		i you he she we they very so too just also still now really
+		secret private confidential unknown none nothing hidden irrelevant
+		important long hard difficult weird strange funny simple common
+		same different spelled spelt written like on in at of for with
+		from and or but to as by if when because please sorry
+		na dey don go wetin wey e im una dem
+		ni ko je se ti re wa
+		bu m ya anyi ha nke onye
+		ne ce ba shi`) {
+		nameStopWords[w] = true
+	}
+}
+
+var nameWordPattern = regexp.MustCompile(`\S+`)
+
+// trimName returns how many bytes of value, a name found after a cue, to
+// redact: none when it starts with a common word, and without trailing
+// common words ("my name is Ada I have a fever").
+func trimName(value string) int {
+	end := 0
+	for i, loc := range nameWordPattern.FindAllStringIndex(value, -1) {
+		if nameStopWords[strings.TrimSpace(NormalizeForMatch(value[loc[0]:loc[1]]))] {
+			if i == 0 {
+				return 0
+			}
+			break
+		}
+		end = loc[1]
+	}
+	return end
+}
+
+// minLearnedName is the shortest name, or part of one, that is redacted
+// wherever it appears once found.
+const minLearnedName = 3
+
+// DefaultPIIRules cover emails, Nigerian and international phone numbers,
+// 11-digit NIN/BVN numbers, and names introduced by a cue in English,
+// Yoruba, Igbo, Hausa or Pidgin. Phone numbers come before IDs because
+// local numbers are 11 digits too.
+var DefaultPIIRules = PIIRules{
+	Rules: []PIIRule{
+		{"email", PIIEmail, `(?i)[a-z0-9._%+-]+@[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}`},
+		{"phone_ng", PIIPhone, `(?:\+?234[\s-]?|\b0)[789][01]\d(?:[\s-]?\d){7}\b`},
+		{"phone_intl", PIIPhone, `\+\d{1,3}(?:[\s-]?\d){7,12}\b`},
+		{"nin_bvn", PIIID, `\b\d{11}\b`},
+		{"name_title", PIIName, `\b(?:Mr|Mrs|Ms|Miss|Dr|Chief|Alhaji|Alhaja|Mallam|Mama|Baba)\.?\s+(\p{Lu}[\p{L}\p{M}'’-]*(?:\s+\p{Lu}[\p{L}\p{M}'’-]*)?)`},
+		{"name_cue_en", PIIName, `(?i:my name is|my names are)\s+` + nameWords},
+		{"name_cue_yo", PIIName, `(?i:or[uú]\p{M}*k[oọ]\p{M}*\s+mi\s+ni)\s+` + nameWords},
+		{"name_cue_ig", PIIName, `(?i:aha\s+m\s+b[uụ]\p{M}*)\s+` + nameWords},
+		{"name_cue_ha", PIIName, `(?i:sunana|sunan a)\s+` + nameWords},
+		{"name_cue_pcm", PIIName, `(?i:my name na)\s+` + nameWords},
+	},
+}
+
+type piiRule struct {
+	PIIRule
+	re *regexp.Regexp
+	// literal rules match a known name rather than a cue, so their matches
+	// are not checked with trimName.
+	literal bool
+}
+
+// PIIRedactor finds personal data with compiled rules.
+type PIIRedactor struct {
+	rules []piiRule
+}
+
+// NewPIIRedactor compiles rules; invalid patterns are an error.
+func NewPIIRedactor(rules PIIRules) (*PIIRedactor, error) {
+	r := &PIIRedactor{}
+	for _, rule := range rules.Rules {
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("PII rule %q: %w", rule.Name, err)
+		}
+		rule.Type = piiType(rule.Type)
+		if rule.Type == "" {
+			rule.Type = PIIName
+		}
+		r.rules = append(r.rules, piiRule{PIIRule: rule, re: re})
+	}
+	for _, name := range rules.Names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		r.rules = append(r.rules, piiRule{PIIRule: PIIRule{Name: "name_list", Type: PIIName}, re: wholeWord(name), literal: true})
+	}
+	return r, nil
+}
+
+// piiType upper-cases t and drops what can't appear in a placeholder.
+func piiType(t string) string {
+	return strings.Trim(strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z':
+			return r
+		case r == '_' || r == ' ' || r == '-':
+			return '_'
+		}
+		return -1
+	}, t), "_")
+}
+
+// wholeWord matches s as whole words in any case; the match is group 1.
+func wholeWord(s string) *regexp.Regexp {
+	return regexp.MustCompile(`(?i)(?:^|[^\p{L}\p{M}])(` + regexp.QuoteMeta(s) + `)(?:[^\p{L}\p{M}]|$)`)
+}
+
+// LoadPIIRules returns the default rules followed by those in the JSON file
+// in PII_RULES_FILE (same shape as PIIRules), if set.
+func LoadPIIRules() (PIIRules, error) {
+	rules := PIIRules{Rules: append([]PIIRule(nil), DefaultPIIRules.Rules...)}
+	path := strings.TrimSpace(os.Getenv("PII_RULES_FILE"))
+	if path == "" {
+		return rules, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return rules, fmt.Errorf("read PII rules: %w", err)
+	}
+	var extra PIIRules
+	if err := json.Unmarshal(raw, &extra); err != nil {
+		return rules, fmt.Errorf("parse PII rules: %w", err)
+	}
+	rules.Rules = append(rules.Rules, extra.Rules...)
+	rules.Names = append(rules.Names, extra.Names...)
+	return rules, nil
+}
+
+var (
+	piiOnce     sync.Once
+	piiRedactor *PIIRedactor
+	// sharingRedactor backs sharingPIIRedactor when PII_REDACTION=off.
+	sharingOnce     sync.Once
+	sharingRedactor *PIIRedactor
+	// redactionLogger receives every redaction; set by SetRedactionLogger.
+	redactionLogger func(models.RedactionLog)
+)
+
+// defaultPIIRedactor is built from LoadPIIRules on first use, or is nil
+// when PII_REDACTION=off.
+func defaultPIIRedactor() *PIIRedactor {
+	piiOnce.Do(func() {
+		if strings.EqualFold(strings.TrimSpace(os.Getenv("PII_REDACTION")), "off") {
+			log.Printf("PII: redaction is disabled")
+			return
+		}
+		piiRedactor = loadPIIRedactor()
+	})
+	return piiRedactor
+}
+
+// sharingPIIRedactor redacts content shared outside the owner's account.
+// PII_REDACTION only covers what is sent to outside services, so this one
+// is built even when it is off.
+func sharingPIIRedactor() *PIIRedactor {
+	if p := defaultPIIRedactor(); p != nil {
+		return p
+	}
+	sharingOnce.Do(func() {
+		sharingRedactor = loadPIIRedactor()
+	})
+	return sharingRedactor
+}
+
+// loadPIIRedactor builds a redactor from LoadPIIRules, falling back to the
+// built-in rules if those fail.
+func loadPIIRedactor() *PIIRedactor {
+	rules, err := LoadPIIRules()
+	var p *PIIRedactor
+	if err == nil {
+		p, err = NewPIIRedactor(rules)
+	}
+	if err != nil {
+		log.Printf("PII: %v; using built-in rules", err)
+		p, _ = NewPIIRedactor(DefaultPIIRules)
+	}
+	return p
+}
+
+// SetRedactionLogger makes fn receive a record of every redaction. It is
+// called once at startup.
+func SetRedactionLogger(fn func(models.RedactionLog)) {
+	redactionLogger = fn
+}
+
+// placeholderPattern also accepts placeholders a translator has respaced
+// or recased, like "[ phone_1 ]".
+var placeholderPattern = regexp.MustCompile(`(?i)\[\s*([A-Z]+(?:_[A-Z]+)*)[\s_]*(\d+)\s*\]`)
+
+// Redaction replaces personal data in the texts of one outbound call with
+// placeholders, the same value always getting the same one, and puts the
+// values back into the reply. Once a name has been found, later mentions
+// of it are redacted too, even without a cue.
+type Redaction struct {
+	redactor *PIIRedactor
+	byValue  map[string]string // type + value -> placeholder
+	values   map[string]string // placeholder -> value
+	counts   map[string]int
+	names    []piiRule // one rule per name found
+	learned  map[string]bool
+	Entities []models.RedactedEntity
+}
+
+// NewRedaction starts a redaction with the configured rules; it changes
+// nothing when redaction is disabled.
+func NewRedaction() *Redaction {
+	return defaultPIIRedactor().NewRedaction()
+}
+
+// NewRedaction starts a redaction with p's rules; a nil p changes nothing.
+func (p *PIIRedactor) NewRedaction() *Redaction {
+	return &Redaction{
+		redactor: p,
+		byValue:  map[string]string{},
+		values:   map[string]string{},
+		counts:   map[string]int{},
+		learned:  map[string]bool{},
+	}
+}
+
+type piiNamesKey struct{}
+
+// WithPIINames returns a context whose LLM calls also redact names, such
+// as the account holder's, that no cue introduces.
+func WithPIINames(ctx context.Context, names ...string) context.Context {
+	prev, _ := ctx.Value(piiNamesKey{}).([]string)
+	return context.WithValue(ctx, piiNamesKey{}, append(append([]string(nil), prev...), names...))
+}
+
+// newRedactionFor is NewRedaction knowing the names on ctx.
+func newRedactionFor(ctx context.Context) *Redaction {
+	r := NewRedaction()
+	names, _ := ctx.Value(piiNamesKey{}).([]string)
+	for _, name := range names {
+		r.AddName(name)
+	}
+	return r
+}
+
+// AddName makes r redact name wherever it appears, like a name in the
+// configured name list.
+func (r *Redaction) AddName(name string) {
+	name = strings.TrimSpace(name)
+	if r.redactor == nil || name == "" || r.learned[strings.ToLower(name)] {
+		return
+	}
+	r.learned[strings.ToLower(name)] = true
+	r.names = append(r.names, piiRule{PIIRule: PIIRule{Name: "name_list", Type: PIIName}, re: wholeWord(name), literal: true})
+}
+
+type piiSpan struct {
+	start, end int
+	rule       piiRule
+}
+
+// Redact returns text with its personal data replaced by placeholders.
+func (r *Redaction) Redact(text string) string {
+	if r.redactor == nil || text == "" {
+		return text
+	}
+
+	var spans []piiSpan
+	overlaps := func(start, end int) bool {
+		for _, s := range spans {
+			if start < s.end && s.start < end {
+				return true
+			}
+		}
+		return false
+	}
+	rules := append(append([]piiRule(nil), r.redactor.rules...), r.names...)
+	for _, rule := range rules {
+		for _, m := range rule.re.FindAllStringSubmatchIndex(text, -1) {
+			start, end := m[0], m[1]
+			if len(m) >= 4 && m[2] >= 0 {
+				start, end = m[2], m[3]
+			}
+			if rule.Type == PIIName && !rule.literal {
+				end = start + trimName(text[start:end])
+			}
+			if start == end || overlaps(start, end) || placeholderPattern.MatchString(text[start:end]) {
+				continue
+			}
+			spans = append(spans, piiSpan{start, end, rule})
+		}
+	}
+	if len(spans) == 0 {
+		return text
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var b strings.Builder
+	last := 0
+	for _, s := range spans {
+		b.WriteString(text[last:s.start])
+		b.WriteString(r.placeholder(s.rule, text[s.start:s.end]))
+		last = s.end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+func (r *Redaction) placeholder(rule piiRule, value string) string {
+	key := rule.Type + "\x00" + strings.ToLower(value)
+	if p, ok := r.byValue[key]; ok {
+		return p
+	}
+	r.counts[rule.Type]++
+	p := "[" + rule.Type + "_" + strconv.Itoa(r.counts[rule.Type]) + "]"
+	r.byValue[key] = p
+	r.values[p] = value
+	if rule.Type == PIIName {
+		r.learn(rule.Name, value)
+	}
+	r.Entities = append(r.Entities, models.RedactedEntity{Type: rule.Type, Rule: rule.Name, Placeholder: p})
+	return p
+}
+
+// learn redacts the name value, found by the rule called ruleName, in the
+// rest of the texts too. Parts of it are learned as well, to also catch
+// "Chinedu" once "Chinedu Okafor" is known.
+func (r *Redaction) learn(ruleName, value string) {
+	if utf8.RuneCountInString(value) < minLearnedName || r.learned[strings.ToLower(value)] {
+		return
+	}
+	r.learned[strings.ToLower(value)] = true
+	rule := PIIRule{Name: ruleName, Type: PIIName}
+	r.names = append(r.names, piiRule{PIIRule: rule, re: wholeWord(value), literal: true})
+	if words := strings.Fields(value); len(words) > 1 {
+		for _, w := range words {
+			if utf8.RuneCountInString(w) >= minLearnedName && !nameStopWords[strings.TrimSpace(NormalizeForMatch(w))] {
+				r.names = append(r.names, piiRule{PIIRule: rule, re: wholeWord(w), literal: true})
+			}
+		}
+	}
+}
+
+// RedactAll redacts texts together, so a name introduced in one is also
+// redacted where it is mentioned in the others.
+func (r *Redaction) RedactAll(texts []string) []string {
+	for _, t := range texts {
+		r.Redact(t)
+	}
+	out := make([]string, len(texts))
+	for i, t := range texts {
+		out[i] = r.Redact(t)
+	}
+	return out
+}
+
+// Restore puts the redacted values back into text. Placeholders it did not
+// make are left alone.
+func (r *Redaction) Restore(text string) string {
+	if len(r.values) == 0 {
+		return text
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		sub := placeholderPattern.FindStringSubmatch(m)
+		if v, ok := r.values["["+strings.ToUpper(sub[1])+"_"+sub[2]+"]"]; ok {
+			return v
+		}
+		return m
+	})
+}
+
+// piiStandIns are spoken in place of redacted values, by language and
+// type; "" is for types added through PII_RULES_FILE.
+var piiStandIns = map[string]map[string]string{
+	"en":  {PIIPhone: "a phone number", PIIEmail: "an email address", PIIID: "an ID number", PIIName: "a name", "": "personal details"},
+	"yo":  {PIIPhone: "nọ́mbà fóònù kan", PIIEmail: "àdírẹ́sì ímeèlì kan", PIIID: "nọ́mbà ìdánimọ̀ kan", PIIName: "orúkọ kan", "": "àlàyé ara ẹni"},
+	"ig":  {PIIPhone: "nọmba ekwentị", PIIEmail: "adreesị email", PIIID: "nọmba njirimara", PIIName: "otu aha", "": "ozi onwe onye"},
+	"ha":  {PIIPhone: "lambar waya", PIIEmail: "adireshin imel", PIIID: "lambar shaida", PIIName: "wani suna", "": "bayanan sirri"},
+	"pcm": {PIIPhone: "one phone number", PIIEmail: "one email address", PIIID: "one ID number", PIIName: "one name", "": "personal details"},
+}
+
+// Speakable replaces this redaction's placeholders in text with a spoken
+// stand-in in lang ("a phone number"), for outputs such as speech that the
+// values can't be put back into.
+func (r *Redaction) Speakable(text, lang string) string {
+	if len(r.values) == 0 {
+		return text
+	}
+	standIns, ok := piiStandIns[baseLangCode(lang)]
+	if !ok {
+		standIns = piiStandIns["en"]
+	}
+	return placeholderPattern.ReplaceAllStringFunc(text, func(m string) string {
+		sub := placeholderPattern.FindStringSubmatch(m)
+		if _, ok := r.values["["+strings.ToUpper(sub[1])+"_"+sub[2]+"]"]; !ok {
+			return m
+		}
+		if standIn, ok := standIns[strings.ToUpper(sub[1])]; ok {
+			return standIn
+		}
+		return standIns[""]
+	})
+}
+
+// Log records what was redacted for a call to service, if anything.
+func (r *Redaction) Log(service string) {
+	if len(r.Entities) == 0 {
+		return
+	}
+	placeholders := make([]string, len(r.Entities))
+	for i, e := range r.Entities {
+		placeholders[i] = e.Placeholder
+	}
+	log.Printf("PII: redacted %s before calling %s", strings.Join(placeholders, ", "), service)
+	if redactionLogger != nil {
+		redactionLogger(models.RedactionLog{Service: service, Entities: r.Entities})
+	}
+}
+
+// RedactPII is Redact and Log for a single text sent to service.
+func RedactPII(service, text string) (string, *Redaction) {
+	r := NewRedaction()
+	out := r.Redact(text)
+	r.Log(service)
+	return out, r
+}
+
+// streamRestorer restores placeholders in streamed text, holding back a
+// trailing fragment that may be the start of one.
+type streamRestorer struct {
+	r       *Redaction
+	pending string
+}
+
+// maxPlaceholderLen bounds how much text is held back waiting for "]".
+const maxPlaceholderLen = 24
+
+func (s *streamRestorer) Write(delta string) string {
+	text := s.pending + delta
+	cut := len(text)
+	if i := strings.LastIndex(text, "["); i >= 0 && len(text)-i < maxPlaceholderLen && !strings.Contains(text[i:], "]") {
+		cut = i
+	}
+	s.pending = text[cut:]
+	return s.r.Restore(text[:cut])
+}
+
+func (s *streamRestorer) Flush() string {
+	out := s.r.Restore(s.pending)
+	s.pending = ""
+	return out
+}
@@ -0,0 +1,155 @@
+package services
+
+import (
+	"strings"
+	"testing"
+)
+
+func newTestRedaction(t *testing.T) *Redaction {
+	t.Helper()
+	redactor, err := NewPIIRedactor(DefaultPIIRules)
+	if err != nil {
+		t.Fatalf("NewPIIRedactor: %v", err)
+	}
+	return redactor.NewRedaction()
+}
+
+func TestRedactNameCues(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"en", "Hello, my name is Chinedu Okafor and I have a cough", "Hello, my name is [NAME_1] and I have a cough"},
+		{"en lowercase", "my name is chinedu, I have a fever", "my name is [NAME_1], I have a fever"},
+		{"en trailing pronoun", "My name is Ada I have a headache", "My name is [NAME_1] I have a headache"},
+		{"yo", "Orúkọ mi ni Adébáyọ̀, ara mi kò yá", "Orúkọ mi ni [NAME_1], ara mi kò yá"},
+		{"ig", "Aha m bụ Ngozi Eze. Isi na-awa m ọwụwa", "Aha m bụ [NAME_1]. Isi na-awa m ọwụwa"},
+		{"ha", "Sunana Aminu Bello, ina da zazzaɓi", "Sunana [NAME_1], ina da zazzaɓi"},
+		{"pcm", "My name na Tunde, belle dey pain me", "My name na [NAME_1], belle dey pain me"},
+		{"title", "Dr. Bello said I should rest", "Dr. [NAME_1] said I should rest"},
+		{"phone", "call me on 0803 123 4567", "call me on [PHONE_1]"},
+		{"email", "write to ada.eze@example.com", "write to [EMAIL_1]"},
+		{"nin", "my NIN is 12345678901", "my NIN is [ID_1]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newTestRedaction(t).Redact(tt.text); got != tt.want {
+				t.Errorf("Redact(%q)\n got %q\nwant %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactFalsePositives(t *testing.T) {
+	tests := []struct {
+		name  string
+		texts []string
+	}{
+		{"secret", []string{"You are a helpful assistant.", "my name is a secret, I have a fever"}},
+		{"not important", []string{"The pain is not sharp.", "my name is not important"}},
+		{"the", []string{"Take the tablets after food.", "My name is the least of my worries"}},
+		{"pidgin", []string{"Wetin dey do you?", "my name na wetin una go call me"}},
+		{"yo", []string{"Kò sí ìṣòro.", "Orúkọ mi ni kò ṣe pàtàkì"}},
+		{"ig", []string{"Onye ọ bụla nwere ike ịrịa ọrịa.", "Aha m bụ onye ọrịa"}},
+		{"ha", []string{"Ba ni da lafiya.", "Sunana ba komai ba ne"}},
+		{"no cue", []string{"I have a fever and a cough since Monday.", "Is paracetamol safe for children?"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRedaction(t)
+			got := r.RedactAll(tt.texts)
+			for i := range tt.texts {
+				if got[i] != tt.texts[i] {
+					t.Errorf("RedactAll changed %q to %q", tt.texts[i], got[i])
+				}
+			}
+			if len(r.Entities) != 0 {
+				t.Errorf("unexpected entities %+v", r.Entities)
+			}
+		})
+	}
+}
+
+func TestRedactAllLearnsNames(t *testing.T) {
+	r := newTestRedaction(t)
+	got := r.RedactAll([]string{
+		"Is Chinedu's fever serious?",
+		"my name is Chinedu Okafor",
+		"Okafor family has a history of asthma",
+	})
+	want := []string{
+		"Is [NAME_3]'s fever serious?",
+		"my name is [NAME_1]",
+		"[NAME_2] family has a history of asthma",
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("text %d: got %q, want %q", i, got[i], want[i])
+		}
+	}
+
+	reply := "Thank you [NAME_1]. [ name_3 ]'s fever needs a doctor."
+	if restored := r.Restore(reply); restored != "Thank you Chinedu Okafor. Chinedu's fever needs a doctor." {
+		t.Errorf("Restore = %q", restored)
+	}
+}
+
+func TestRedactShortNamesNotLearned(t *testing.T) {
+	r := newTestRedaction(t)
+	got := r.RedactAll([]string{"my name is Jo", "Jo, take a jog to feel better"})
+	if got[0] != "my name is [NAME_1]" {
+		t.Errorf("cue not redacted: %q", got[0])
+	}
+	if got[1] != "Jo, take a jog to feel better" {
+		t.Errorf("short name learned: %q", got[1])
+	}
+}
+
+func TestStreamRestorer(t *testing.T) {
+	r := newTestRedaction(t)
+	r.Redact("my name is Ngozi")
+	s := &streamRestorer{r: r}
+	var out strings.Builder
+	for _, delta := range []string{"Hello [NA", "ME_", "1], how", " are you?"} {
+		out.WriteString(s.Write(delta))
+	}
+	out.WriteString(s.Flush())
+	if out.String() != "Hello Ngozi, how are you?" {
+		t.Errorf("got %q", out.String())
+	}
+}
+
+func TestRedactAddName(t *testing.T) {
+	r := newTestRedaction(t)
+	r.AddName("Grace Adeyemi")
+	got := r.Redact("You are a patient language tutor. The learner's name is Grace Adeyemi.")
+	if got != "You are a patient language tutor. The learner's name is [NAME_1]." {
+		t.Errorf("got %q", got)
+	}
+	if restored := r.Restore("Well done, [NAME_1]!"); restored != "Well done, Grace Adeyemi!" {
+		t.Errorf("Restore = %q", restored)
+	}
+}
+
+func TestRedactSpeakable(t *testing.T) {
+	tests := []struct {
+		lang string
+		text string
+		want string
+	}{
+		{"en", "Call 0803 123 4567 for an appointment with Dr. Bello", "Call a phone number for an appointment with Dr. a name"},
+		{"yo-NG", "Pe 0803 123 4567", "Pe nọ́mbà fóònù kan"},
+		{"ha", "Aika zuwa ada.eze@example.com", "Aika zuwa adireshin imel"},
+		{"fr", "Appelez 0803 123 4567", "Appelez a phone number"},
+		{"en", "Nothing personal here", "Nothing personal here"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.lang+" "+tt.text, func(t *testing.T) {
+			r := newTestRedaction(t)
+			if got := r.Speakable(r.Redact(tt.text), tt.lang); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
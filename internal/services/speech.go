@@ -0,0 +1,146 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/developia-II/language-translator-backend/internal/audio"
+)
+
+// SSMLSupport describes how much SSML a TTS provider understands.
+type SSMLSupport int
+
+const (
+	// SSMLNone providers only take plain text; SSML is emulated by
+	// synthesizing each segment separately and inserting silence.
+	SSMLNone SSMLSupport = iota
+	// SSMLBreaks providers accept inline <break/> tags but nothing else.
+	SSMLBreaks
+	// SSMLFull providers accept a <speak> document as-is.
+	SSMLFull
+)
+
+// TTSProvider is a text-to-speech backend used by Speak.
+type TTSProvider struct {
+	Name string
+	SSML SSMLSupport
+	// Synthesize returns audio bytes and their content type. Providers that
+	// can't vary prosody ignore p.
+	Synthesize func(text, lang string, p Prosody) ([]byte, string, error)
+}
+
+var (
+	ESpeakProvider = TTSProvider{
+		Name:       "eSpeak",
+		SSML:       SSMLNone,
+		Synthesize: ESpeakTTSWithProsody,
+	}
+	GoogleProvider = TTSProvider{
+		Name:       "Google",
+		SSML:       SSMLFull,
+		Synthesize: withoutPII("Google", GoogleCloudTTS),
+	}
+	ElevenLabsProvider = TTSProvider{
+		Name:       "ElevenLabs",
+		SSML:       SSMLBreaks,
+		Synthesize: withoutPII("ElevenLabs", ElevenLabsTTS),
+	}
+	HuggingFaceProvider = TTSProvider{
+		Name:       "HuggingFace",
+		SSML:       SSMLNone,
+		Synthesize: withoutPII("HuggingFace", SynthesizeTTS),
+	}
+)
+
+// withoutPII wraps a remote TTS service so personal data is left out of
+// the text it is sent; unlike text, audio can't have it put back, so a
+// stand-in such as "a phone number" is spoken instead.
+func withoutPII(service string, synthesize func(text, lang string) ([]byte, string, error)) func(string, string, Prosody) ([]byte, string, error) {
+	return func(text, lang string, _ Prosody) ([]byte, string, error) {
+		redacted, redaction := RedactPII("tts:"+service, text)
+		speakable := redaction.Speakable(redacted, lang)
+		if strings.TrimSpace(speakable) == "" {
+			return nil, "", fmt.Errorf("%s: no text to speak", service)
+		}
+		return synthesize(speakable, lang)
+	}
+}
+
+// Speak synthesizes doc with provider, passing SSML through when the provider
+// supports it and emulating it otherwise. Empty audio is reported as an error.
+func Speak(provider TTSProvider, doc *SpeechDocument, lang string) ([]byte, string, error) {
+	audioBytes, ctype, err := speak(provider, doc, lang)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(audioBytes) == 0 {
+		return nil, "", fmt.Errorf("%s returned empty audio", provider.Name)
+	}
+	return audioBytes, ctype, nil
+}
+
+func speak(provider TTSProvider, doc *SpeechDocument, lang string) ([]byte, string, error) {
+	if !doc.IsSSML {
+		return provider.Synthesize(doc.PlainText(), lang, Prosody{})
+	}
+
+	switch provider.SSML {
+	case SSMLFull:
+		return provider.Synthesize(doc.SSML(), lang, Prosody{})
+	case SSMLBreaks:
+		return provider.Synthesize(doc.TextWithBreaks(), lang, Prosody{})
+	}
+
+	audioBytes, err := emulateSSML(provider, doc, lang)
+	if err != nil {
+		// Emulation needs WAV from the provider; fall back to one plain call
+		// rather than failing the request.
+		log.Printf("TTS: SSML emulation failed for provider=%s: %v; using plain text", provider.Name, err)
+		return provider.Synthesize(doc.PlainText(), lang, Prosody{})
+	}
+	return audioBytes, "audio/wav", nil
+}
+
+// emulateSSML synthesizes every text segment separately and joins them with
+// generated silence for the breaks.
+func emulateSSML(provider TTSProvider, doc *SpeechDocument, lang string) ([]byte, error) {
+	var out *audio.Buffer
+
+	for _, seg := range doc.Segments {
+		if seg.Text == "" {
+			// ParseSSML trims leading pauses, so out is set by now
+			if out == nil {
+				continue
+			}
+			if err := out.Append(audio.Silence(seg.Break, out.SampleRate, out.Channels)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		data, ctype, err := provider.Synthesize(seg.Text, lang, seg.Prosody)
+		if err != nil {
+			return nil, err
+		}
+		if !audio.IsWAV(data) {
+			return nil, fmt.Errorf("provider returned %s, need audio/wav", ctype)
+		}
+		buf, err := audio.DecodeWAV(data)
+		if err != nil {
+			return nil, err
+		}
+
+		if out == nil {
+			out = &audio.Buffer{SampleRate: buf.SampleRate, Channels: buf.Channels}
+		}
+		if err := out.Append(buf); err != nil {
+			return nil, err
+		}
+	}
+
+	if out == nil {
+		return nil, fmt.Errorf("no audio produced")
+	}
+	return audio.EncodeWAV(out), nil
+}
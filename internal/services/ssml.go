@@ -0,0 +1,419 @@
+package services
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxSSMLBreak mirrors the limit most cloud providers enforce on <break>.
+const maxSSMLBreak = 10 * time.Second
+
+// Prosody carries the <prosody> attributes in effect for a segment.
+// Values are kept as written (e.g. "slow", "80%", "+2st", "loud").
+type Prosody struct {
+	Rate   string `json:"rate,omitempty"`
+	Pitch  string `json:"pitch,omitempty"`
+	Volume string `json:"volume,omitempty"`
+}
+
+// SpeechSegment is either a run of text to speak or a pause. Phoneme holds
+// an IPA pronunciation for Text when one comes from the lexicon.
+type SpeechSegment struct {
+	Text    string        `json:"text,omitempty"`
+	Phoneme string        `json:"phoneme,omitempty"`
+	Break   time.Duration `json:"break,omitempty"`
+	Prosody Prosody       `json:"prosody,omitempty"`
+}
+
+// SpeechDocument is the parsed form of a TTS request. Plain text requests
+// become a single segment; SSML is flattened into text and break segments
+// with <say-as> and <sub> already expanded.
+type SpeechDocument struct {
+	Segments []SpeechSegment `json:"segments"`
+	IsSSML   bool            `json:"isSsml"`
+}
+
+// IsSSML reports whether text is wrapped in a <speak> element.
+func IsSSML(text string) bool {
+	return strings.HasPrefix(strings.TrimSpace(text), "<speak")
+}
+
+// PlainSpeech wraps text in a single-segment document.
+func PlainSpeech(text string) *SpeechDocument {
+	return &SpeechDocument{Segments: []SpeechSegment{{Text: strings.TrimSpace(text)}}}
+}
+
+// ParseSpeech returns a SpeechDocument for text, parsing it as SSML when it
+// starts with <speak>.
+func ParseSpeech(text string) (*SpeechDocument, error) {
+	if IsSSML(text) {
+		return ParseSSML(text)
+	}
+	return PlainSpeech(text), nil
+}
+
+// ParseSSML parses the supported SSML subset: <speak>, <p>, <s>, <break>,
+// <prosody>, <say-as interpret-as="digits|date"> and <sub alias>.
+// Unknown elements are rejected so clients learn early what is supported.
+func ParseSSML(input string) (*SpeechDocument, error) {
+	dec := xml.NewDecoder(strings.NewReader(strings.TrimSpace(input)))
+	dec.Strict = true
+
+	doc := &SpeechDocument{IsSSML: true}
+	prosody := []Prosody{{}}
+
+	// Text inside <say-as> / <sub> is collected and rewritten on close.
+	var special *xml.StartElement
+	var specialText strings.Builder
+
+	sawSpeak := false
+	depth := 0
+
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid SSML: %w", err)
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			depth++
+			name := t.Name.Local
+			if depth == 1 {
+				if name != "speak" {
+					return nil, fmt.Errorf("invalid SSML: root element must be <speak>, got <%s>", name)
+				}
+				sawSpeak = true
+				continue
+			}
+			if special != nil {
+				return nil, fmt.Errorf("invalid SSML: <%s> is not allowed inside <%s>", name, special.Name.Local)
+			}
+			switch name {
+			case "p", "s":
+				doc.addBreak(0)
+			case "break":
+				d, err := parseSSMLBreak(t)
+				if err != nil {
+					return nil, err
+				}
+				doc.addBreak(d)
+			case "prosody":
+				p := prosody[len(prosody)-1]
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "rate":
+						p.Rate = a.Value
+					case "pitch":
+						p.Pitch = a.Value
+					case "volume":
+						p.Volume = a.Value
+					}
+				}
+				prosody = append(prosody, p)
+			case "say-as", "sub":
+				el := t.Copy()
+				special = &el
+				specialText.Reset()
+			default:
+				return nil, fmt.Errorf("invalid SSML: unsupported element <%s>", name)
+			}
+		case xml.EndElement:
+			depth--
+			switch t.Name.Local {
+			case "p", "s":
+				doc.addBreak(0)
+			case "prosody":
+				if len(prosody) > 1 {
+					prosody = prosody[:len(prosody)-1]
+				}
+			case "say-as", "sub":
+				text, err := expandSSMLSpecial(special, specialText.String())
+				if err != nil {
+					return nil, err
+				}
+				doc.addText(text, prosody[len(prosody)-1])
+				special = nil
+			}
+		case xml.CharData:
+			if special != nil {
+				specialText.Write(t)
+				continue
+			}
+			if depth > 0 {
+				doc.addText(string(t), prosody[len(prosody)-1])
+			}
+		}
+	}
+
+	if !sawSpeak {
+		return nil, fmt.Errorf("invalid SSML: missing <speak> element")
+	}
+	doc.trim()
+	if doc.PlainText() == "" {
+		return nil, fmt.Errorf("invalid SSML: no text to speak")
+	}
+	return doc, nil
+}
+
+// addText appends text, merging with the previous segment when prosody matches.
+func (d *SpeechDocument) addText(text string, p Prosody) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	if n := len(d.Segments); n > 0 {
+		last := &d.Segments[n-1]
+		if last.Break == 0 && last.Text != "" && last.Prosody == p {
+			last.Text += " " + text
+			return
+		}
+	}
+	d.Segments = append(d.Segments, SpeechSegment{Text: text, Prosody: p})
+}
+
+// addBreak appends a pause. A zero duration only splits text segments
+// (sentence / paragraph boundaries) and is dropped by trim.
+func (d *SpeechDocument) addBreak(dur time.Duration) {
+	if n := len(d.Segments); n > 0 && d.Segments[n-1].Text == "" {
+		d.Segments[n-1].Break += dur
+		if d.Segments[n-1].Break > maxSSMLBreak {
+			d.Segments[n-1].Break = maxSSMLBreak
+		}
+		return
+	}
+	d.Segments = append(d.Segments, SpeechSegment{Break: dur})
+}
+
+// trim drops empty separators and leading/trailing pauses.
+func (d *SpeechDocument) trim() {
+	out := d.Segments[:0]
+	for _, s := range d.Segments {
+		if s.Text == "" && s.Break == 0 {
+			continue
+		}
+		out = append(out, s)
+	}
+	for len(out) > 0 && out[0].Text == "" {
+		out = out[1:]
+	}
+	for len(out) > 0 && out[len(out)-1].Text == "" {
+		out = out[:len(out)-1]
+	}
+	d.Segments = out
+}
+
+// PlainText returns the spoken text with all markup removed.
+func (d *SpeechDocument) PlainText() string {
+	parts := make([]string, 0, len(d.Segments))
+	for _, s := range d.Segments {
+		if s.Text != "" {
+			parts = append(parts, s.Text)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// SSML renders the document back to normalized SSML for providers that
+// accept it natively.
+func (d *SpeechDocument) SSML() string {
+	var b strings.Builder
+	b.WriteString("<speak>")
+	for _, s := range d.Segments {
+		if s.Text == "" {
+			fmt.Fprintf(&b, `<break time="%dms"/>`, s.Break.Milliseconds())
+			continue
+		}
+		if s.Prosody == (Prosody{}) {
+			writeSSMLText(&b, s)
+			b.WriteString(" ")
+			continue
+		}
+		b.WriteString("<prosody")
+		if s.Prosody.Rate != "" {
+			fmt.Fprintf(&b, ` rate="%s"`, xmlAttr(s.Prosody.Rate))
+		}
+		if s.Prosody.Pitch != "" {
+			fmt.Fprintf(&b, ` pitch="%s"`, xmlAttr(s.Prosody.Pitch))
+		}
+		if s.Prosody.Volume != "" {
+			fmt.Fprintf(&b, ` volume="%s"`, xmlAttr(s.Prosody.Volume))
+		}
+		b.WriteString(">")
+		writeSSMLText(&b, s)
+		b.WriteString("</prosody> ")
+	}
+	b.WriteString("</speak>")
+	return b.String()
+}
+
+// TextWithBreaks renders the document as plain text with inline
+// <break time="..."/> tags, the only markup ElevenLabs understands.
+func (d *SpeechDocument) TextWithBreaks() string {
+	parts := make([]string, 0, len(d.Segments))
+	for _, s := range d.Segments {
+		if s.Text == "" {
+			parts = append(parts, fmt.Sprintf(`<break time="%.1fs" />`, s.Break.Seconds()))
+			continue
+		}
+		parts = append(parts, s.Text)
+	}
+	return strings.Join(parts, " ")
+}
+
+func writeSSMLText(b *strings.Builder, s SpeechSegment) {
+	if s.Phoneme == "" {
+		xml.EscapeText(b, []byte(s.Text))
+		return
+	}
+	fmt.Fprintf(b, `<phoneme alphabet="ipa" ph="%s">`, xmlAttr(s.Phoneme))
+	xml.EscapeText(b, []byte(s.Text))
+	b.WriteString("</phoneme>")
+}
+
+func xmlAttr(v string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(v))
+	return b.String()
+}
+
+func parseSSMLBreak(el xml.StartElement) (time.Duration, error) {
+	var d time.Duration
+	for _, a := range el.Attr {
+		switch a.Name.Local {
+		case "time":
+			v := strings.TrimSpace(a.Value)
+			parsed, err := time.ParseDuration(v)
+			if err != nil {
+				return 0, fmt.Errorf("invalid SSML: bad break time %q", a.Value)
+			}
+			d = parsed
+		case "strength":
+			switch a.Value {
+			case "none":
+				d = 0
+			case "x-weak":
+				d = 100 * time.Millisecond
+			case "weak":
+				d = 250 * time.Millisecond
+			case "medium":
+				d = 500 * time.Millisecond
+			case "strong":
+				d = 750 * time.Millisecond
+			case "x-strong":
+				d = time.Second
+			default:
+				return 0, fmt.Errorf("invalid SSML: bad break strength %q", a.Value)
+			}
+		}
+	}
+	if len(el.Attr) == 0 {
+		d = 500 * time.Millisecond
+	}
+	if d < 0 {
+		d = 0
+	}
+	if d > maxSSMLBreak {
+		d = maxSSMLBreak
+	}
+	return d, nil
+}
+
+func expandSSMLSpecial(el *xml.StartElement, inner string) (string, error) {
+	attr := func(name string) string {
+		for _, a := range el.Attr {
+			if a.Name.Local == name {
+				return a.Value
+			}
+		}
+		return ""
+	}
+	inner = strings.TrimSpace(inner)
+
+	if el.Name.Local == "sub" {
+		alias := strings.TrimSpace(attr("alias"))
+		if alias == "" {
+			return "", fmt.Errorf("invalid SSML: <sub> requires an alias attribute")
+		}
+		return alias, nil
+	}
+
+	switch attr("interpret-as") {
+	case "digits", "characters":
+		return spellCharacters(inner), nil
+	case "date":
+		return spellDate(inner, attr("format"))
+	default:
+		return "", fmt.Errorf("invalid SSML: say-as interpret-as must be digits or date")
+	}
+}
+
+// spellCharacters separates each character so engines read "500" as
+// "five zero zero" rather than "five hundred".
+func spellCharacters(s string) string {
+	var parts []string
+	for _, r := range s {
+		if r == ' ' || r == ',' {
+			continue
+		}
+		parts = append(parts, string(r))
+	}
+	return strings.Join(parts, " ")
+}
+
+var ssmlMonths = []string{
+	"January", "February", "March", "April", "May", "June",
+	"July", "August", "September", "October", "November", "December",
+}
+
+// spellDate expands a numeric date using the say-as format (ymd, dmy, mdy;
+// default ymd) into "1 May 2024" so engines don't read it as arithmetic.
+func spellDate(s, format string) (string, error) {
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return r == '-' || r == '/' || r == '.' || r == ' '
+	})
+	if format == "" {
+		format = "ymd"
+	}
+	if len(fields) != len(format) {
+		return "", fmt.Errorf("invalid SSML: date %q does not match format %q", s, format)
+	}
+
+	var day, month, year int
+	for i, f := range format {
+		n, err := strconv.Atoi(fields[i])
+		if err != nil {
+			return "", fmt.Errorf("invalid SSML: bad date %q", s)
+		}
+		switch f {
+		case 'd':
+			day = n
+		case 'm':
+			month = n
+		case 'y':
+			year = n
+		default:
+			return "", fmt.Errorf("invalid SSML: bad date format %q", format)
+		}
+	}
+	if month < 1 || month > 12 || day < 0 || day > 31 {
+		return "", fmt.Errorf("invalid SSML: bad date %q", s)
+	}
+
+	parts := []string{}
+	if day > 0 {
+		parts = append(parts, strconv.Itoa(day))
+	}
+	parts = append(parts, ssmlMonths[month-1])
+	if year > 0 {
+		parts = append(parts, strconv.Itoa(year))
+	}
+	return strings.Join(parts, " "), nil
+}
@@ -0,0 +1,261 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sashabaranov/go-openai"
+)
+
+// Transcript is the result of speech-to-text.
+type Transcript struct {
+	Text     string              `json:"text"`
+	Language string              `json:"language"`
+	Duration float64             `json:"duration,omitempty"` // seconds
+	Segments []TranscriptSegment `json:"segments"`
+	Provider string              `json:"provider"`
+}
+
+// TranscriptSegment is a timed span of the transcript, in seconds.
+type TranscriptSegment struct {
+	Start float64 `json:"start"`
+	End   float64 `json:"end"`
+	Text  string  `json:"text"`
+}
+
+// STTProvider is a speech-to-text backend. lang is an optional hint.
+type STTProvider struct {
+	Name       string
+	Transcribe func(ctx context.Context, audio []byte, filename, lang string) (*Transcript, error)
+}
+
+// WhisperProvider transcribes through the Whisper-compatible endpoint of
+// the LLM providers that have a transcription model.
+func WhisperProvider(s *LLMService) STTProvider {
+	return STTProvider{Name: "Whisper", Transcribe: s.Transcribe}
+}
+
+// MMSProvider transcribes with Hugging Face MMS ASR models. It doesn't
+// detect language, so the hint is echoed back.
+var MMSProvider = STTProvider{Name: "MMS", Transcribe: TranscribeMMS}
+
+// whisperLanguages maps Whisper's language names (verbose_json) to codes.
+var whisperLanguages = map[string]string{
+	"english": "en",
+	"yoruba":  "yo",
+	"hausa":   "ha",
+	"igbo":    "ig",
+	"french":  "fr",
+	"arabic":  "ar",
+	"swahili": "sw",
+}
+
+// Transcribe sends audio to the Whisper-compatible transcription endpoint
+// of each provider with a transcription model in turn, and returns segment
+// timings.
+func (s *LLMService) Transcribe(ctx context.Context, audio []byte, filename, lang string) (*Transcript, error) {
+	var resp openai.AudioResponse
+	err := s.failover(ctx, UseTranscribe, func(ctx context.Context, p LLMProvider, model string) error {
+		var err error
+		resp, err = p.CreateTranscription(ctx, openai.AudioRequest{
+			Model:                  model,
+			FilePath:               filename,
+			Reader:                 bytes.NewReader(audio),
+			Language:               baseLangCode(lang),
+			Format:                 openai.AudioResponseFormatVerboseJSON,
+			TimestampGranularities: []openai.TranscriptionTimestampGranularity{openai.TranscriptionTimestampGranularitySegment},
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("whisper API error: %w", err)
+	}
+
+	detected := strings.ToLower(strings.TrimSpace(resp.Language))
+	if code, ok := whisperLanguages[detected]; ok {
+		detected = code
+	}
+	if detected == "" {
+		detected = baseLangCode(lang)
+	}
+
+	t := &Transcript{
+		Text:     strings.TrimSpace(resp.Text),
+		Language: detected,
+		Duration: resp.Duration,
+		Provider: "Whisper",
+		Segments: make([]TranscriptSegment, 0, len(resp.Segments)),
+	}
+	for _, s := range resp.Segments {
+		t.Segments = append(t.Segments, TranscriptSegment{Start: s.Start, End: s.End, Text: strings.TrimSpace(s.Text)})
+	}
+	return t, nil
+}
+
+// mmsLanguages are the ISO 639-3 codes of MMS's language adapters.
+var mmsLanguages = map[string]string{
+	"en":  "eng",
+	"yo":  "yor",
+	"ig":  "ibo",
+	"ha":  "hau",
+	"pcm": "pcm",
+	"fr":  "fra",
+	"ar":  "ara",
+	"sw":  "swh",
+}
+
+// TranscribeMMS posts raw audio to a Hugging Face MMS ASR model. Models are
+// picked per language with STT_YOR_MODEL / STT_IBO_MODEL / STT_HAU_MODEL,
+// falling back to the multilingual STT_MMS_MODEL (default
+// facebook/mms-1b-all). The multilingual model is told the language's
+// adapter as target_lang, since it otherwise decodes everything as
+// English; it needs a language it has an adapter for.
+func TranscribeMMS(ctx context.Context, audio []byte, filename, lang string) (*Transcript, error) {
+	token := strings.TrimSpace(os.Getenv("HF_API_TOKEN"))
+	if token == "" {
+		return nil, fmt.Errorf("HF_API_TOKEN is not configured")
+	}
+
+	code := baseLangCode(lang)
+	var model string
+	switch code {
+	case "yo":
+		model = os.Getenv("STT_YOR_MODEL")
+	case "ig":
+		model = os.Getenv("STT_IBO_MODEL")
+	case "ha":
+		model = os.Getenv("STT_HAU_MODEL")
+	}
+	model = strings.TrimSpace(model)
+
+	body := audio
+	contentType := AudioContentType(audio, filename)
+	if model == "" {
+		model = strings.TrimSpace(os.Getenv("STT_MMS_MODEL"))
+		if model == "" {
+			model = "facebook/mms-1b-all"
+		}
+		adapter, ok := mmsLanguages[code]
+		if !ok {
+			return nil, fmt.Errorf("MMS needs a supported language hint, got %q", lang)
+		}
+		payload, err := json.Marshal(map[string]any{
+			"inputs":     base64.StdEncoding.EncodeToString(audio),
+			"parameters": map[string]string{"target_lang": adapter},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("encode request: %w", err)
+		}
+		body, contentType = payload, "application/json"
+	}
+
+	apiURL := fmt.Sprintf("https://api-inference.huggingface.co/models/%s", model)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, apiURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Wait-For-Model", "true")
+	req.Header.Set("User-Agent", "language-translator-backend/stt (+github.com/developia-II)")
+
+	client := &http.Client{Timeout: 120 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("call Hugging Face: %w", err)
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		preview := string(respBody)
+		if len(preview) > 500 {
+			preview = preview[:500] + "..."
+		}
+		return nil, fmt.Errorf("huggingface %d: %s", resp.StatusCode, preview)
+	}
+
+	var out struct {
+		Text   string `json:"text"`
+		Chunks []struct {
+			Text      string    `json:"text"`
+			Timestamp []float64 `json:"timestamp"`
+		} `json:"chunks"`
+	}
+	if err := json.Unmarshal(respBody, &out); err != nil {
+		return nil, fmt.Errorf("invalid JSON from huggingface: %w", err)
+	}
+
+	t := &Transcript{
+		Text:     strings.TrimSpace(out.Text),
+		Language: code,
+		Provider: "MMS",
+		Segments: []TranscriptSegment{},
+	}
+	for _, ch := range out.Chunks {
+		seg := TranscriptSegment{Text: strings.TrimSpace(ch.Text)}
+		if len(ch.Timestamp) == 2 {
+			seg.Start, seg.End = ch.Timestamp[0], ch.Timestamp[1]
+		}
+		t.Segments = append(t.Segments, seg)
+	}
+	if len(t.Segments) == 0 && t.Text != "" {
+		t.Segments = append(t.Segments, TranscriptSegment{Text: t.Text})
+	}
+	return t, nil
+}
+
+// AudioContentType sniffs WAV, MP3, WebM and Ogg uploads, falling back to
+// the file extension. It returns "" for anything else.
+func AudioContentType(data []byte, filename string) string {
+	switch {
+	case len(data) >= 12 && string(data[0:4]) == "RIFF" && string(data[8:12]) == "WAVE":
+		return "audio/wav"
+	case len(data) >= 3 && string(data[0:3]) == "ID3",
+		len(data) >= 2 && data[0] == 0xFF && data[1]&0xE0 == 0xE0:
+		return "audio/mpeg"
+	case len(data) >= 4 && bytes.Equal(data[0:4], []byte{0x1A, 0x45, 0xDF, 0xA3}):
+		return "audio/webm"
+	case len(data) >= 4 && string(data[0:4]) == "OggS":
+		return "audio/ogg"
+	}
+
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".wav"):
+		return "audio/wav"
+	case strings.HasSuffix(name, ".mp3"):
+		return "audio/mpeg"
+	case strings.HasSuffix(name, ".webm"):
+		return "audio/webm"
+	}
+	return ""
+}
+
+// baseLangCode turns "yo-NG" / "yor" / "Yoruba" into "yo"; unknown values
+// pass through lowercased without a region.
+func baseLangCode(lang string) string {
+	l := strings.ToLower(strings.TrimSpace(strings.ReplaceAll(lang, "_", "-")))
+	if i := strings.IndexByte(l, '-'); i > 0 {
+		l = l[:i]
+	}
+	switch l {
+	case "yor", "yoruba":
+		return "yo"
+	case "ibo", "igbo":
+		return "ig"
+	case "hau", "hausa":
+		return "ha"
+	case "eng", "english":
+		return "en"
+	}
+	return l
+}
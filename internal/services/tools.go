@@ -0,0 +1,105 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+)
+
+// defaultToolIterations bounds how many rounds of tool calls one answer
+// may take before the model is told to answer without tools.
+const defaultToolIterations = 4
+
+// Tool is a function the chat model may call. Parameters is its JSON
+// schema; Run gets the model's JSON arguments and returns a JSON-encodable
+// result.
+type Tool struct {
+	Name        string
+	Description string
+	Parameters  map[string]any
+	Run         func(ctx context.Context, args json.RawMessage) (any, error)
+}
+
+// ChatWithTools is Chat with function calling. Each round the model may
+// call tools; their results are fed back until it answers in text or
+// maxIterations rounds have run (0 means the default), after which it must
+// answer without tools. The calls and results are returned as message parts.
+func (s *LLMService) ChatWithTools(ctx context.Context, messages []openai.ChatCompletionMessage, tools []Tool, maxIterations int) (string, []models.MessagePart, error) {
+	if maxIterations <= 0 {
+		maxIterations = defaultToolIterations
+	}
+
+	defs := make([]openai.Tool, len(tools))
+	byName := make(map[string]Tool, len(tools))
+	for i, t := range tools {
+		defs[i] = openai.Tool{
+			Type: openai.ToolTypeFunction,
+			Function: &openai.FunctionDefinition{
+				Name:        t.Name,
+				Description: t.Description,
+				Parameters:  t.Parameters,
+			},
+		}
+		byName[t.Name] = t
+	}
+
+	msgs := append([]openai.ChatCompletionMessage(nil), messages...)
+	var parts []models.MessagePart
+	for iteration := 0; ; iteration++ {
+		req := openai.ChatCompletionRequest{
+			Messages:    msgs,
+			Temperature: 0.7,
+			MaxTokens:   1000,
+		}
+		if iteration < maxIterations {
+			req.Tools = defs
+		}
+
+		resp, err := s.complete(ctx, UseChat, req)
+		if err != nil {
+			return "", parts, err
+		}
+
+		reply := resp.Choices[0].Message
+		if len(reply.ToolCalls) == 0 || iteration >= maxIterations {
+			parts = append(parts, models.MessagePart{Type: models.PartText, Text: reply.Content})
+			return reply.Content, parts, nil
+		}
+
+		msgs = append(msgs, reply)
+		for _, call := range reply.ToolCalls {
+			parts = append(parts, models.MessagePart{
+				Type:       models.PartToolCall,
+				ToolCallID: call.ID,
+				Name:       call.Function.Name,
+				Arguments:  call.Function.Arguments,
+			})
+
+			result := models.MessagePart{Type: models.PartToolResult, ToolCallID: call.ID, Name: call.Function.Name}
+			content := ""
+			if tool, ok := byName[call.Function.Name]; !ok {
+				result.Error = "unknown tool " + call.Function.Name
+			} else if out, err := tool.Run(ctx, json.RawMessage(call.Function.Arguments)); err != nil {
+				result.Error = err.Error()
+			} else if encoded, err := json.Marshal(out); err != nil {
+				result.Error = "encode result: " + err.Error()
+			} else {
+				result.Result = string(encoded)
+				content = result.Result
+			}
+			if result.Error != "" {
+				encoded, _ := json.Marshal(map[string]string{"error": result.Error})
+				content = string(encoded)
+			}
+			parts = append(parts, result)
+
+			msgs = append(msgs, openai.ChatCompletionMessage{
+				Role:       openai.ChatMessageRoleTool,
+				Content:    content,
+				ToolCallID: call.ID,
+			})
+		}
+	}
+}
@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -24,19 +25,43 @@ type LibreTranslateResponse struct {
     TranslatedText string `json:"translatedText"`
 }
 
-func TranslateText(text, sourceLang, targetLang string) (string, error) {
+// TranslateText redacts personal data from text, translates it with the
+// first service that succeeds, and puts the data back into the result.
+// The LLM fallback runs on ctx, so its tokens count towards a WithUsage
+// counter.
+func TranslateText(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
+    redacted, redaction := RedactPII("translate", text)
+    translated, err := translateRedacted(ctx, redacted, sourceLang, targetLang)
+    if err != nil {
+        return "", err
+    }
+    return redaction.Restore(translated), nil
+}
+
+func translateRedacted(ctx context.Context, text, sourceLang, targetLang string) (string, error) {
     // 1) Try MyMemory first (free, no API key; better support for Nigerian languages)
     if translated, err := translateWithMyMemory(text, sourceLang, targetLang); err == nil && translated != "" {
         return translated, nil
     }
 
     // 2) Fallback to LibreTranslate (public instance or configured URL)
-    if translated, err := translateWithLibreTranslate(text, sourceLang, targetLang); err == nil && translated != "" {
+    translated, err := translateWithLibreTranslate(text, sourceLang, targetLang)
+    if err == nil && translated != "" {
         return translated, nil
-    } else if err != nil {
-        return "", fmt.Errorf("translation failed: %w", err)
     }
 
+    // 3) Last resort: the LLM providers' translate model
+    if llm := LLM(); llm != nil && llm.Serves(UseTranslate) {
+        ctx, cancel := context.WithTimeout(ctx, 60*time.Second)
+        defer cancel()
+        if out, llmErr := llm.Translate(ctx, text, sourceLang, targetLang); llmErr == nil && out != "" {
+            return out, nil
+        }
+    }
+
+    if err != nil {
+        return "", fmt.Errorf("translation failed: %w", err)
+    }
     return "", fmt.Errorf("all translation services failed")
 }
 
@@ -0,0 +1,158 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+	"github.com/sashabaranov/go-openai"
+)
+
+// triageRepairAttempts is how many times the model is asked to fix output
+// that failed validation before giving up.
+const triageRepairAttempts = 2
+
+// triageInstructions is added to the system prompt in triage mode.
+const triageInstructions = `Answer with a single JSON object and nothing else, using exactly these keys:
+{"possibleCauses": [string], "selfCare": [string], "warningSigns": [string], "urgency": "self_care" | "routine" | "urgent" | "emergency", "seeDoctorIf": [string]}
+possibleCauses, warningSigns and seeDoctorIf must not be empty. Write the strings in the response language; keep the keys and the urgency value in English.`
+
+// ErrInvalidTriage wraps validation failures of the model's triage JSON.
+var ErrInvalidTriage = errors.New("invalid triage output")
+
+// ParseTriage extracts and validates the triage object in raw. Code fences
+// and text around the outermost braces are ignored.
+func ParseTriage(raw string) (*models.Triage, error) {
+	start := strings.Index(raw, "{")
+	end := strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object found", ErrInvalidTriage)
+	}
+
+	dec := json.NewDecoder(strings.NewReader(raw[start : end+1]))
+	dec.DisallowUnknownFields()
+	var t models.Triage
+	if err := dec.Decode(&t); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTriage, err)
+	}
+
+	t.PossibleCauses = compactList(t.PossibleCauses)
+	t.SelfCare = compactList(t.SelfCare)
+	t.WarningSigns = compactList(t.WarningSigns)
+	t.SeeDoctorIf = compactList(t.SeeDoctorIf)
+	t.Urgency = strings.ToLower(strings.TrimSpace(t.Urgency))
+
+	var problems []string
+	if len(t.PossibleCauses) == 0 {
+		problems = append(problems, "possibleCauses is empty")
+	}
+	if len(t.WarningSigns) == 0 {
+		problems = append(problems, "warningSigns is empty")
+	}
+	if len(t.SeeDoctorIf) == 0 {
+		problems = append(problems, "seeDoctorIf is empty")
+	}
+	switch t.Urgency {
+	case models.UrgencySelfCare, models.UrgencyRoutine, models.UrgencyUrgent, models.UrgencyEmergency:
+	default:
+		problems = append(problems, fmt.Sprintf("urgency %q is not one of self_care, routine, urgent, emergency", t.Urgency))
+	}
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTriage, strings.Join(problems, "; "))
+	}
+	return &t, nil
+}
+
+func compactList(items []string) []string {
+	out := make([]string, 0, len(items))
+	for _, s := range items {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Triage asks for a structured answer. Output that fails validation is sent
+// back to the model with the errors for repair. The last raw output is
+// returned even when every attempt fails.
+func (s *LLMService) Triage(ctx context.Context, messages []openai.ChatCompletionMessage) (string, *models.Triage, error) {
+	msgs := append([]openai.ChatCompletionMessage(nil), messages...)
+	raw := ""
+	var lastErr error
+	for attempt := 0; attempt <= triageRepairAttempts; attempt++ {
+		resp, err := s.complete(ctx, UseChat, openai.ChatCompletionRequest{
+			Messages:       msgs,
+			Temperature:    0.3,
+			MaxTokens:      1000,
+			ResponseFormat: &openai.ChatCompletionResponseFormat{Type: openai.ChatCompletionResponseFormatTypeJSONObject},
+		})
+		if err != nil {
+			return raw, nil, err
+		}
+
+		raw = resp.Choices[0].Message.Content
+		t, err := ParseTriage(raw)
+		if err == nil {
+			return raw, t, nil
+		}
+		lastErr = err
+		msgs = append(msgs,
+			openai.ChatCompletionMessage{Role: openai.ChatMessageRoleAssistant, Content: raw},
+			openai.ChatCompletionMessage{
+				Role:    openai.ChatMessageRoleUser,
+				Content: "That reply was not valid (" + err.Error() + "). Reply again with only the corrected JSON object.",
+			},
+		)
+	}
+	return raw, nil, lastErr
+}
+
+// TriageLabels are the headings of a rendered triage answer.
+type TriageLabels struct {
+	Urgency        string
+	PossibleCauses string
+	SelfCare       string
+	WarningSigns   string
+	SeeDoctorIf    string
+}
+
+// EnglishTriageLabels are the headings RenderTriage uses.
+var EnglishTriageLabels = TriageLabels{
+	Urgency:        "Urgency",
+	PossibleCauses: "Possible causes",
+	SelfCare:       "Self-care",
+	WarningSigns:   "Warning signs",
+	SeeDoctorIf:    "See a doctor if",
+}
+
+// RenderTriage turns a triage answer into readable Markdown for clients
+// that do not render the structure, and for the conversation history.
+func RenderTriage(t *models.Triage) string {
+	return RenderTriageWith(t, EnglishTriageLabels, strings.ReplaceAll(t.Urgency, "_", " "))
+}
+
+// RenderTriageWith is RenderTriage with the given headings and urgency
+// wording, for answers translated from English.
+func RenderTriageWith(t *models.Triage, labels TriageLabels, urgency string) string {
+	var b strings.Builder
+	section := func(title string, items []string) {
+		if len(items) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "**%s**\n", title)
+		for _, item := range items {
+			fmt.Fprintf(&b, "- %s\n", item)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "**%s:** %s\n\n", labels.Urgency, urgency)
+	section(labels.PossibleCauses, t.PossibleCauses)
+	section(labels.SelfCare, t.SelfCare)
+	section(labels.WarningSigns, t.WarningSigns)
+	section(labels.SeeDoctorIf, t.SeeDoctorIf)
+	return strings.TrimSpace(b.String())
+}
@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/developia-II/language-translator-backend/internal/models"
+)
+
+// modelPrice is USD per million prompt and completion tokens.
+type modelPrice struct {
+	Prompt     float64
+	Completion float64
+}
+
+// defaultPrices are list prices at the time of writing; LLM_PRICING
+// overrides or extends them. Unknown models cost nothing.
+var defaultPrices = map[string]modelPrice{
+	"llama-3.1-70b-versatile": {0.59, 0.79},
+	"llama-3.3-70b-versatile": {0.59, 0.79},
+	"llama-3.1-8b-instant":    {0.05, 0.08},
+	"gpt-4o-mini":             {0.15, 0.60},
+	"gpt-4o":                  {2.50, 10.00},
+}
+
+var (
+	pricesOnce sync.Once
+	prices     map[string]modelPrice
+)
+
+// loadPrices reads LLM_PRICING, e.g.
+// "llama3.1=0/0,llama-3.3-70b-versatile=0.59/0.79", in USD per million
+// prompt/completion tokens.
+func loadPrices() map[string]modelPrice {
+	out := make(map[string]modelPrice, len(defaultPrices))
+	for m, p := range defaultPrices {
+		out[m] = p
+	}
+	for _, entry := range strings.Split(os.Getenv("LLM_PRICING"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		model, rates, ok := strings.Cut(entry, "=")
+		in, outRate, ok2 := strings.Cut(rates, "/")
+		p, err1 := strconv.ParseFloat(strings.TrimSpace(in), 64)
+		c, err2 := strconv.ParseFloat(strings.TrimSpace(outRate), 64)
+		if !ok || !ok2 || err1 != nil || err2 != nil {
+			log.Printf("LLM: ignoring LLM_PRICING entry %q", entry)
+			continue
+		}
+		out[strings.TrimSpace(model)] = modelPrice{p, c}
+	}
+	return out
+}
+
+// EstimateCost prices a call to model in USD.
+func EstimateCost(model string, promptTokens, completionTokens int) float64 {
+	pricesOnce.Do(func() { prices = loadPrices() })
+	p := prices[model]
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// Usage adds up the tokens of every LLM call made with a context from
+// WithUsage. It is safe for concurrent use.
+type Usage struct {
+	mu    sync.Mutex
+	total models.TokenUsage
+}
+
+type usageKey struct{}
+
+// WithUsage returns a context that counts the tokens of LLM calls made
+// with it, and the counter.
+func WithUsage(ctx context.Context) (context.Context, *Usage) {
+	u := &Usage{}
+	return context.WithValue(ctx, usageKey{}, u), u
+}
+
+// addUsage counts a call on ctx's counter, if it has one.
+func addUsage(ctx context.Context, model string, promptTokens, completionTokens int, estimated bool) {
+	u, _ := ctx.Value(usageKey{}).(*Usage)
+	if u == nil {
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.total.PromptTokens += promptTokens
+	u.total.CompletionTokens += completionTokens
+	u.total.TotalTokens += promptTokens + completionTokens
+	u.total.CostUSD += EstimateCost(model, promptTokens, completionTokens)
+	u.total.Model = model
+	u.total.Estimated = u.total.Estimated || estimated
+}
+
+// Total is the usage counted so far; nil when nothing was used.
+func (u *Usage) Total() *models.TokenUsage {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if u.total.TotalTokens == 0 {
+		return nil
+	}
+	total := u.total
+	return &total
+}
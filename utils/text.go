@@ -0,0 +1,15 @@
+package utils
+
+import "strings"
+
+// previewRunes is the length of message previews in conversation lists.
+const previewRunes = 120
+
+// Preview collapses whitespace in s and cuts it to a short one-line preview.
+func Preview(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if runes := []rune(s); len(runes) > previewRunes {
+		return string(runes[:previewRunes]) + "…"
+	}
+	return s
+}